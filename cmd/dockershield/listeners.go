@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+
+	"dockershield/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// boundListener is an open listener with its configuration
+type boundListener struct {
+	net.Listener
+	config *config.Listener
+}
+
+// openListeners opens every configured socket and address
+func openListeners(listeners []*config.Listener, logger *logrus.Logger) []*boundListener {
+	bound := make([]*boundListener, 0, len(listeners))
+	for _, listener := range listeners {
+		if listener.Socket != "" {
+			bound = append(bound, &boundListener{Listener: openUnixListener(listener, logger), config: listener})
+		} else {
+			bound = append(bound, &boundListener{Listener: openTCPListener(listener, logger), config: listener})
+		}
+	}
+	return bound
+}
+
+// listenerNameOf returns the configured name of an open listener
+func listenerNameOf(listeners []*boundListener, l net.Listener) string {
+	for _, listener := range listeners {
+		if listener == l {
+			return listener.config.Name
+		}
+	}
+	return ""
+}
+
+// serveListener serves HTTP requests on one listener until the server shuts down
+func serveListener(srv *http.Server, listener *boundListener, logger *logrus.Logger) {
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		logger.Fatalf("Failed to start server on %s: %v", listener.config.Name, err)
+	}
+}
+
+// openUnixListener creates a Unix socket with its permissions and ownership
+func openUnixListener(listener *config.Listener, logger *logrus.Logger) net.Listener {
+	socketPath := stripUnixPrefix(listener.Socket)
+
+	logger.Infof("Listening on Unix socket: %s", socketPath)
+
+	// Remove existing socket file if it exists
+	os.Remove(socketPath)
+
+	l, err := net.Listen("unix", socketPath)
+	if err != nil {
+		logger.Fatalf("Failed to create Unix socket: %v", err)
+	}
+
+	// Set socket permissions and ownership
+	setSocketPermissions(socketPath, listener.Perms, logger)
+	if listener.Owner != "" || listener.Group != "" {
+		setSocketOwnership(socketPath, listener.Owner, listener.Group, logger)
+	}
+
+	return l
+}
+
+// openTCPListener listens on a TCP address, over TLS when configured
+func openTCPListener(listener *config.Listener, logger *logrus.Logger) net.Listener {
+	logger.Infof("Listening on %s", listener.Address)
+
+	l, err := net.Listen("tcp", listener.Address)
+	if err != nil {
+		logger.Fatalf("Failed to listen on %s: %v", listener.Address, err)
+	}
+
+	if listener.TLS == nil {
+		logger.Warnf("TCP listener %s without TLS: anyone reaching this address can use the proxy", listener.Address)
+		return l
+	}
+
+	tlsConfig, err := listener.TLS.ServerConfig()
+	if err != nil {
+		logger.Fatalf("Failed to configure TLS listener %s: %v", listener.Address, err)
+	}
+	if tlsConfig.ClientCAs != nil {
+		logger.Infof("TLS enabled on %s, client certificates: %s", listener.Address, clientAuthMode(listener.TLS))
+	} else {
+		logger.Infof("TLS enabled on %s, client certificates not requested", listener.Address)
+	}
+
+	return tls.NewListener(l, tlsConfig)
+}
+
+// clientAuthMode returns the client certificate mode of the TLS listener
+func clientAuthMode(listenTLS *config.ListenTLS) string {
+	if listenTLS.ClientAuth == "" {
+		return config.ClientAuthRequire
+	}
+	return listenTLS.ClientAuth
+}
+
+// setSocketPermissions sets permissions on a Unix socket
+func setSocketPermissions(socketPath, socketPerms string, logger *logrus.Logger) {
+	if socketPerms == "" {
+		socketPerms = "0666" // Default: accessible by all users
+	}
+
+	// Parse octal permission string
+	perms := os.FileMode(0666) // default
+	if permValue, err := strconv.ParseUint(socketPerms, 8, 32); err != nil {
+		logger.Warnf("Invalid socket permissions '%s', using 0666", socketPerms)
+	} else {
+		perms = os.FileMode(permValue)
+	}
+
+	if err := os.Chmod(socketPath, perms); err != nil {
+		logger.Warnf("Failed to set socket permissions: %v", err)
+	} else {
+		logger.Infof("Socket permissions set to %s", socketPerms)
+	}
+}
+
+// setSocketOwnership changes the owner and group of a Unix socket.
+// Names are resolved from the system databases, numeric IDs are used as is.
+func setSocketOwnership(socketPath, owner, group string, logger *logrus.Logger) {
+	uid, gid := -1, -1
+
+	if owner != "" {
+		id, err := lookupID(owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			logger.Fatalf("Unknown socket owner '%s': %v", owner, err)
+		}
+		uid = id
+	}
+
+	if group != "" {
+		id, err := lookupID(group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			logger.Fatalf("Unknown socket group '%s': %v", group, err)
+		}
+		gid = id
+	}
+
+	if err := os.Chown(socketPath, uid, gid); err != nil {
+		logger.Fatalf("Failed to set socket ownership: %v", err)
+	}
+	logger.Infof("Socket ownership set to %s:%s", owner, group)
+}
+
+// lookupID returns a numeric ID, resolving names with lookup
+func lookupID(value string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(value); err == nil {
+		return id, nil
+	}
+	id, err := lookup(value)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
+// stripUnixPrefix removes "unix://" prefix from socket path
+func stripUnixPrefix(socketPath string) string {
+	if len(socketPath) > 7 && socketPath[:7] == "unix://" {
+		return socketPath[7:]
+	}
+	return socketPath
+}
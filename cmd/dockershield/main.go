@@ -2,15 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
 	"dockershield/config"
+	"dockershield/internal/identity"
 	"dockershield/internal/middleware"
 	"dockershield/internal/proxy"
 	"dockershield/pkg/rules"
@@ -21,7 +22,10 @@ import (
 
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		logrus.Fatalf("Failed to load configuration: %v", err)
+	}
 
 	// Setup logger
 	logger := setupLogger(cfg.LogLevel)
@@ -29,7 +33,7 @@ func main() {
 
 	// Auto-detect Docker API version if not set
 	if cfg.APIVersion == "" {
-		cfg.APIVersion = config.DetectDockerAPIVersion(cfg.DockerSocket, logger)
+		cfg.APIVersion = config.DetectDockerAPIVersion(cfg.DockerSocket, cfg.DockerTLS, logger)
 	} else {
 		logger.Infof("Using configured Docker API version: %s", cfg.APIVersion)
 	}
@@ -38,7 +42,10 @@ func main() {
 	matcher := rules.NewMatcher(cfg.AccessRules)
 
 	// Create proxy handler
-	proxyHandler := proxy.NewHandler(cfg)
+	proxyHandler, err := proxy.NewHandler(cfg)
+	if err != nil {
+		logger.Fatalf("Failed to configure Docker upstream: %v", err)
+	}
 
 	// Setup Gin router
 	if cfg.LogLevel != "debug" {
@@ -48,9 +55,21 @@ func main() {
 	router := gin.New()
 	router.Use(gin.Recovery())
 	router.Use(middleware.LoggingMiddleware(logger))
+	// Rules attached to the listener bind every request it accepts
+	router.Use(middleware.ListenerMiddleware(cfg.Listeners, cfg.AdvancedFilters))
+	// Upstream selection runs before any path-based decision (it may strip a path prefix)
+	router.Use(middleware.UpstreamMiddleware(cfg.Upstreams, cfg.AdvancedFilters, logger))
+	// Caller profiles (mTLS subject, API token, source network, unix peer) narrow the listener and upstream rules
+	router.Use(middleware.ProfileMiddleware(cfg.Profiles, cfg.AdvancedFilters, logger))
+	// Timeout profile (short, default or streaming) is chosen from the request path
+	router.Use(middleware.TimeoutMiddleware(cfg.Timeouts))
 	// Advanced filters run FIRST to allow DKRPRX__ variables to override ACL
-	router.Use(middleware.AdvancedFilterMiddleware(cfg.AdvancedFilters, logger))
+	router.Use(middleware.AdvancedFilterMiddleware(cfg.AdvancedFilters, proxyHandler, logger))
 	router.Use(middleware.ACLMiddleware(matcher))
+	// Operations on existing objects are checked against the caller's ownership labels
+	router.Use(middleware.OwnershipMiddleware(cfg.AdvancedFilters, proxyHandler, logger))
+	// List and inspect answers are trimmed to the objects the caller may see
+	router.Use(middleware.VisibilityMiddleware(cfg.AdvancedFilters, proxyHandler, logger))
 
 	// Register catch-all route for proxying
 	router.Any("/*path", proxyHandler.ProxyRequest)
@@ -58,17 +77,29 @@ func main() {
 	// Log configuration
 	logConfiguration(logger, cfg)
 
-	// Create HTTP server
+	// Open every listener before serving, so that a bad one stops the startup
+	logger.Infof("Proxying to %s", cfg.DockerSocket)
+	listeners := openListeners(cfg.Listeners, logger)
+
+	// Create HTTP server, shared by all listeners
+	// Read/write deadlines are set per request by TimeoutMiddleware so that
+	// streaming endpoints (events, logs -f, pulls, builds) are not cut
 	srv := &http.Server{
 		Handler:           router,
 		ReadHeaderTimeout: 30 * time.Second, // Prevent Slowloris attacks
-		ReadTimeout:       60 * time.Second,
-		WriteTimeout:      60 * time.Second,
 		IdleTimeout:       120 * time.Second,
+		// HTTP/2 cannot hijack connections (attach, exec start)
+		TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){},
+		BaseContext: func(l net.Listener) context.Context {
+			return identity.WithListener(context.Background(), listenerNameOf(listeners, l))
+		},
+		// Record the pid, uid and gid of unix socket callers for logging and profile selection
+		ConnContext: identity.PeerConnContext,
 	}
 
-	// Start server (Unix socket or TCP)
-	go startServer(srv, cfg, logger)
+	for _, listener := range listeners {
+		go serveListener(srv, listener, logger)
+	}
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -85,87 +116,16 @@ func main() {
 		logger.Errorf("Server forced to shutdown: %v", err)
 	}
 
-	// Cleanup Unix socket if used
-	if cfg.ListenSocket != "" {
-		os.Remove(stripUnixPrefix(cfg.ListenSocket))
+	// Cleanup Unix sockets
+	for _, listener := range listeners {
+		if listener.config.Socket != "" {
+			os.Remove(stripUnixPrefix(listener.config.Socket))
+		}
 	}
 
 	logger.Info("Server stopped")
 }
 
-// startServer starts the HTTP server on either Unix socket or TCP
-func startServer(srv *http.Server, cfg *config.Config, logger *logrus.Logger) {
-	if cfg.ListenSocket != "" {
-		startUnixSocketServer(srv, cfg, logger)
-	} else {
-		startTCPServer(srv, cfg, logger)
-	}
-}
-
-// startUnixSocketServer starts the server on a Unix socket
-func startUnixSocketServer(srv *http.Server, cfg *config.Config, logger *logrus.Logger) {
-	socketPath := stripUnixPrefix(cfg.ListenSocket)
-
-	logger.Infof("Listening on Unix socket: %s", socketPath)
-	logger.Infof("Proxying to %s", cfg.DockerSocket)
-
-	// Remove existing socket file if it exists
-	os.Remove(socketPath)
-
-	listener, err := net.Listen("unix", socketPath)
-	if err != nil {
-		logger.Fatalf("Failed to create Unix socket: %v", err)
-	}
-
-	// Set socket permissions
-	setSocketPermissions(socketPath, logger)
-
-	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
-		logger.Fatalf("Failed to start server: %v", err)
-	}
-}
-
-// startTCPServer starts the server on a TCP address
-func startTCPServer(srv *http.Server, cfg *config.Config, logger *logrus.Logger) {
-	logger.Infof("Listening on %s", cfg.ListenAddr)
-	logger.Infof("Proxying to %s", cfg.DockerSocket)
-
-	srv.Addr = cfg.ListenAddr
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logger.Fatalf("Failed to start server: %v", err)
-	}
-}
-
-// setSocketPermissions sets permissions on a Unix socket
-func setSocketPermissions(socketPath string, logger *logrus.Logger) {
-	socketPerms := os.Getenv("SOCKET_PERMS")
-	if socketPerms == "" {
-		socketPerms = "0666" // Default: accessible by all users
-	}
-
-	// Parse octal permission string
-	perms := os.FileMode(0666) // default
-	if permValue, err := strconv.ParseUint(socketPerms, 8, 32); err != nil {
-		logger.Warnf("Invalid SOCKET_PERMS '%s', using 0666", socketPerms)
-	} else {
-		perms = os.FileMode(permValue)
-	}
-
-	if err := os.Chmod(socketPath, perms); err != nil {
-		logger.Warnf("Failed to set socket permissions: %v", err)
-	} else {
-		logger.Infof("Socket permissions set to %s", socketPerms)
-	}
-}
-
-// stripUnixPrefix removes "unix://" prefix from socket path
-func stripUnixPrefix(socketPath string) string {
-	if len(socketPath) > 7 && socketPath[:7] == "unix://" {
-		return socketPath[7:]
-	}
-	return socketPath
-}
-
 // setupLogger configures the logger based on log level
 func setupLogger(level string) *logrus.Logger {
 	logger := logrus.New()
@@ -192,6 +152,32 @@ func logConfiguration(logger *logrus.Logger, cfg *config.Config) {
 	logger.Info("Access Rules Configuration:")
 	logger.Infof("  Granted endpoints: %v", getGrantedEndpoints(*cfg.AccessRules))
 	logger.Infof("  Allowed methods: %v", getAllowedMethods(*cfg.AccessRules))
+	logger.Infof("  Timeouts: short=%s default=%s stream_idle=%s",
+		cfg.Timeouts.Short, cfg.Timeouts.Default, cfg.Timeouts.StreamIdle)
+
+	for _, listener := range cfg.Listeners {
+		if listener.AccessRules != nil {
+			logger.Infof("Listener %s", listener.Name)
+			logger.Infof("  Granted endpoints: %v", getGrantedEndpoints(*listener.AccessRules))
+			logger.Infof("  Allowed methods: %v", getAllowedMethods(*listener.AccessRules))
+		}
+	}
+
+	for _, upstream := range cfg.Upstreams {
+		logger.Infof("Upstream %s -> %s", upstream.Name, upstream.DockerSocket)
+		logger.Infof("  Selectors: listeners=%v client_cidrs=%v path_prefix=%q",
+			upstream.Listeners, upstream.ClientCIDRs, upstream.PathPrefix)
+		logger.Infof("  Granted endpoints: %v", getGrantedEndpoints(*upstream.AccessRules))
+		logger.Infof("  Allowed methods: %v", getAllowedMethods(*upstream.AccessRules))
+	}
+
+	for _, profile := range cfg.Profiles {
+		logger.Infof("Profile %s", profile.Name)
+		logger.Infof("  Selectors: cert_subjects=%v tokens=%d client_cidrs=%v uids=%v gids=%v",
+			profile.CertSubjects, len(profile.Tokens), profile.ClientCIDRs, profile.UIDs, profile.GIDs)
+		logger.Infof("  Granted endpoints: %v", getGrantedEndpoints(*profile.AccessRules))
+		logger.Infof("  Allowed methods: %v", getAllowedMethods(*profile.AccessRules))
+	}
 
 	if !cfg.AccessRules.Post && !cfg.AccessRules.Delete && !cfg.AccessRules.Put {
 		logger.Warn("  ⚠️  Read-only mode enabled (POST, DELETE, PUT disabled)")
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -11,13 +12,19 @@ import (
 // Config holds the application configuration
 type Config struct {
 	ListenAddr      string
-	ListenSocket    string // Unix socket path for listening (optional)
+	ListenSocket    string     // Unix socket path for listening (optional)
+	ListenTLS       *ListenTLS // TLS on the TCP listener (optional)
 	DockerSocket    string
+	DockerTLS       *DockerTLS // TLS towards a tcp:// DOCKER_SOCKET (optional)
 	LogLevel        string
 	APIVersion      string
 	AccessRules     *AccessRules
 	AdvancedFilters *filters.AdvancedFilter // Filtres avancés (optionnel)
 	FiltersPath     string                  // Chemin vers le fichier JSON de filtres
+	Timeouts        *Timeouts               // Timeout profiles per endpoint class
+	Upstreams       []*Upstream             // Additional Docker daemons (optional)
+	Profiles        []*Profile              // Per-caller policies (optional)
+	Listeners       []*Listener             // Sockets and addresses served by the proxy
 }
 
 // AccessRules defines which Docker API endpoints are allowed
@@ -54,12 +61,19 @@ type AccessRules struct {
 	Put    bool
 }
 
-// Load loads configuration from environment variables
-func Load() *Config {
+// Load loads configuration from environment variables and the FILTERS_CONFIG file
+func Load() (*Config, error) {
 	filtersPath := getEnv("FILTERS_CONFIG", "")
 
-	// Charger les filtres depuis JSON (si configuré)
-	jsonFilters := loadAdvancedFilters(filtersPath)
+	// Charger les paramètres du proxy et les filtres depuis JSON (si configuré)
+	fileCfg, err := loadFileConfig(filtersPath)
+	if err != nil {
+		return nil, fmt.Errorf("FILTERS_CONFIG: %w", err)
+	}
+	jsonFilters, err := loadAdvancedFilters(filtersPath)
+	if err != nil {
+		return nil, fmt.Errorf("FILTERS_CONFIG: %w", err)
+	}
 
 	// Charger les filtres depuis les variables d'environnement (prioritaire)
 	envFilters := LoadFiltersFromEnv()
@@ -75,14 +89,20 @@ func Load() *Config {
 	config := &Config{
 		ListenAddr:      getEnv("LISTEN_ADDR", ":2375"),
 		ListenSocket:    getEnv("LISTEN_SOCKET", ""),
+		ListenTLS:       loadListenTLS(),
 		DockerSocket:    getEnv("DOCKER_SOCKET", "unix:///var/run/docker.sock"),
+		DockerTLS:       loadDockerTLS(),
 		LogLevel:        getEnv("LOG_LEVEL", "info"),
 		APIVersion:      getEnv("API_VERSION", ""), // Will be auto-detected if empty
 		AccessRules:     loadAccessRules(),
 		FiltersPath:     filtersPath,
 		AdvancedFilters: mergedFilters,
+		Timeouts:        loadTimeouts(fileCfg.Timeouts),
+		Upstreams:       loadUpstreams(fileCfg.Upstreams),
+		Profiles:        loadProfiles(fileCfg.Profiles),
 	}
-	return config
+	config.Listeners = loadListeners(fileCfg.Listeners, config)
+	return config, nil
 }
 
 // loadAccessRules loads access rules from environment variables
@@ -121,6 +141,47 @@ func loadAccessRules() *AccessRules {
 	}
 }
 
+// accessRulesFromGrants builds access rules from named grants such as
+// {"containers": true, "post": true}. Names are the environment variable
+// names (case-insensitive), unlisted rules keep their default value.
+func accessRulesFromGrants(grants map[string]bool) *AccessRules {
+	rules := &AccessRules{Events: true, Ping: true, Version: true}
+	fields := map[string]*bool{
+		"EVENTS":       &rules.Events,
+		"PING":         &rules.Ping,
+		"VERSION":      &rules.Version,
+		"AUTH":         &rules.Auth,
+		"BUILD":        &rules.Build,
+		"COMMIT":       &rules.Commit,
+		"CONFIGS":      &rules.Configs,
+		"CONTAINERS":   &rules.Containers,
+		"DISTRIBUTION": &rules.Distribution,
+		"EXEC":         &rules.Exec,
+		"IMAGES":       &rules.Images,
+		"INFO":         &rules.Info,
+		"NETWORKS":     &rules.Networks,
+		"NODES":        &rules.Nodes,
+		"PLUGINS":      &rules.Plugins,
+		"SECRETS":      &rules.Secrets,
+		"SERVICES":     &rules.Services,
+		"SESSION":      &rules.Session,
+		"SWARM":        &rules.Swarm,
+		"SYSTEM":       &rules.System,
+		"TASKS":        &rules.Tasks,
+		"VOLUMES":      &rules.Volumes,
+		"POST":         &rules.Post,
+		"DELETE":       &rules.Delete,
+		"PUT":          &rules.Put,
+	}
+
+	for name, granted := range grants {
+		if field, ok := fields[strings.ToUpper(name)]; ok {
+			*field = granted
+		}
+	}
+	return rules
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -154,20 +215,20 @@ func getBoolEnv(key string, defaultValue bool) bool {
 }
 
 // loadAdvancedFilters loads advanced filters from JSON file
-func loadAdvancedFilters(filtersPath string) *filters.AdvancedFilter {
+func loadAdvancedFilters(filtersPath string) (*filters.AdvancedFilter, error) {
 	if filtersPath == "" {
-		return nil
+		return nil, nil
 	}
 
 	data, err := os.ReadFile(filtersPath)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to read %s: %w", filtersPath, err)
 	}
 
 	filter, err := filters.LoadFromJSON(data)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to parse %s: %w", filtersPath, err)
 	}
 
-	return filter
+	return filter, nil
 }
@@ -1,8 +1,19 @@
 package config
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"dockershield/pkg/filters"
 )
 
 func TestGetEnv(t *testing.T) {
@@ -240,7 +251,10 @@ func TestLoad(t *testing.T) {
 		cleanEnv()
 		defer cleanEnv()
 
-		cfg := Load()
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
 
 		if cfg.ListenAddr != ":2375" {
 			t.Errorf("Expected ListenAddr ':2375', got '%s'", cfg.ListenAddr)
@@ -272,7 +286,10 @@ func TestLoad(t *testing.T) {
 		os.Setenv("LOG_LEVEL", "debug")
 		os.Setenv("API_VERSION", "1.41")
 
-		cfg := Load()
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
 
 		if cfg.ListenAddr != ":3000" {
 			t.Errorf("Expected ListenAddr ':3000', got '%s'", cfg.ListenAddr)
@@ -291,6 +308,24 @@ func TestLoad(t *testing.T) {
 		}
 	})
 
+	t.Run("Invalid FILTERS_CONFIG", func(t *testing.T) {
+		cleanEnv()
+		defer cleanEnv()
+
+		dir := t.TempDir()
+		invalid := filepath.Join(dir, "invalid.json")
+		if err := os.WriteFile(invalid, []byte(`{"upstreams": [`), 0600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+
+		for _, path := range []string{filepath.Join(dir, "missing.json"), invalid} {
+			os.Setenv("FILTERS_CONFIG", path)
+			if cfg, err := Load(); err == nil {
+				t.Errorf("Expected an error for %s, got %+v", path, cfg)
+			}
+		}
+	})
+
 	t.Run("Unix socket formats", func(t *testing.T) {
 		cleanEnv()
 		defer cleanEnv()
@@ -315,7 +350,10 @@ func TestLoad(t *testing.T) {
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
 				os.Setenv("LISTEN_SOCKET", tt.envValue)
-				cfg := Load()
+				cfg, err := Load()
+				if err != nil {
+					t.Fatalf("Load failed: %v", err)
+				}
 				if cfg.ListenSocket != tt.expected {
 					t.Errorf("Expected '%s', got '%s'", tt.expected, cfg.ListenSocket)
 				}
@@ -392,3 +430,752 @@ func TestAccessRulesDefaults(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"Empty value returns default", "", 42 * time.Second},
+		{"Plain seconds", "90", 90 * time.Second},
+		{"Go duration", "5m", 5 * time.Minute},
+		{"Zero disables", "0", 0},
+		{"Invalid value returns default", "soon", 42 * time.Second},
+		{"Negative value returns default", "-5s", 42 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseDuration(tt.value, 42*time.Second)
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoadTimeouts(t *testing.T) {
+	cleanEnv := func() {
+		for _, v := range []string{"TIMEOUT_SHORT", "TIMEOUT_DEFAULT", "TIMEOUT_STREAM_IDLE"} {
+			os.Unsetenv(v)
+		}
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		cleanEnv()
+
+		timeouts := loadTimeouts(nil)
+		if timeouts.Short != 30*time.Second {
+			t.Errorf("Expected short timeout 30s, got %s", timeouts.Short)
+		}
+		if timeouts.Default != 60*time.Second {
+			t.Errorf("Expected default timeout 60s, got %s", timeouts.Default)
+		}
+		if timeouts.StreamIdle != 0 {
+			t.Errorf("Expected stream idle timeout disabled, got %s", timeouts.StreamIdle)
+		}
+	})
+
+	t.Run("JSON file overridden by environment", func(t *testing.T) {
+		cleanEnv()
+		defer cleanEnv()
+
+		os.Setenv("TIMEOUT_STREAM_IDLE", "10m")
+
+		timeouts := loadTimeouts(&timeoutsFile{Short: "5s", StreamIdle: "1m"})
+		if timeouts.Short != 5*time.Second {
+			t.Errorf("Expected short timeout from JSON 5s, got %s", timeouts.Short)
+		}
+		if timeouts.Default != 60*time.Second {
+			t.Errorf("Expected default timeout 60s, got %s", timeouts.Default)
+		}
+		if timeouts.StreamIdle != 10*time.Minute {
+			t.Errorf("Expected stream idle timeout from env 10m, got %s", timeouts.StreamIdle)
+		}
+	})
+
+	t.Run("Loaded from FILTERS_CONFIG", func(t *testing.T) {
+		cleanEnv()
+
+		path := filepath.Join(t.TempDir(), "filters.json")
+		content := `{"timeouts": {"default": "2m"}, "images": {"denied_tags": ["^latest$"]}}`
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		os.Setenv("FILTERS_CONFIG", path)
+		defer os.Unsetenv("FILTERS_CONFIG")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load failed: %v", err)
+		}
+		if cfg.Timeouts.Default != 2*time.Minute {
+			t.Errorf("Expected default timeout 2m, got %s", cfg.Timeouts.Default)
+		}
+		if cfg.AdvancedFilters.Images == nil {
+			t.Error("Expected image filters to be loaded from the same file")
+		}
+	})
+}
+
+func TestLoadUpstreams(t *testing.T) {
+	os.Unsetenv("DKRPRX__DISABLE_DEFAULTS")
+
+	upstreams := loadUpstreams([]*upstreamFile{
+		{
+			Name:         "rootless",
+			DockerSocket: "unix:///run/user/1000/docker.sock",
+			PathPrefix:   "rootless/",
+			AccessRules:  map[string]bool{"containers": true, "POST": true, "events": false},
+			Filters:      &filters.AdvancedFilter{},
+		},
+		{Name: "no-selector", DockerSocket: "unix:///var/run/docker.sock"},
+		{Name: "", DockerSocket: "unix:///var/run/docker.sock", PathPrefix: "/anonymous"},
+		{Name: "ci", DockerSocket: "tcp://10.0.0.5:2375", ClientCIDRs: []string{"10.1.0.0/16"}},
+	})
+
+	if len(upstreams) != 2 {
+		t.Fatalf("Expected 2 valid upstreams, got %d", len(upstreams))
+	}
+
+	rootless := upstreams[0]
+	if rootless.PathPrefix != "/rootless" {
+		t.Errorf("Expected normalized prefix '/rootless', got '%s'", rootless.PathPrefix)
+	}
+	if !rootless.AccessRules.Containers || !rootless.AccessRules.Post {
+		t.Error("Expected CONTAINERS and POST to be granted")
+	}
+	if rootless.AccessRules.Events {
+		t.Error("Expected EVENTS to be revoked")
+	}
+	if !rootless.AccessRules.Ping || rootless.AccessRules.Images {
+		t.Error("Expected unlisted rules to keep their defaults")
+	}
+	if rootless.AdvancedFilters == nil || rootless.AdvancedFilters.Volumes == nil {
+		t.Error("Expected default security filters on upstreams")
+	}
+
+	if upstreams[1].Name != "ci" || len(upstreams[1].ClientCIDRs) != 1 {
+		t.Errorf("Unexpected second upstream: %+v", upstreams[1])
+	}
+	if upstreams[1].AdvancedFilters != nil {
+		t.Error("Expected an upstream without filters to use the global filters")
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	os.Unsetenv("DKRPRX__DISABLE_DEFAULTS")
+
+	profiles := loadProfiles([]*profileFile{
+		{
+			Name:         "traefik",
+			CertSubjects: []string{"traefik"},
+			AccessRules:  map[string]bool{"containers": true, "networks": true},
+			Filters:      &filters.AdvancedFilter{},
+		},
+		{Name: "no-selector", AccessRules: map[string]bool{"containers": true}},
+		{Name: "", Tokens: []string{"secret"}},
+		{Name: "ci", Tokens: []string{"secret"}, ClientCIDRs: []string{"10.1.0.0/16"}},
+		{Name: "monitoring", UIDs: []uint32{1001}, GIDs: []uint32{1001}},
+	})
+
+	if len(profiles) != 3 {
+		t.Fatalf("Expected 3 valid profiles, got %d", len(profiles))
+	}
+
+	traefik := profiles[0]
+	if !traefik.AccessRules.Containers || !traefik.AccessRules.Networks || traefik.AccessRules.Post {
+		t.Errorf("Unexpected traefik rules: %+v", traefik.AccessRules)
+	}
+	if traefik.AdvancedFilters == nil || traefik.AdvancedFilters.Volumes == nil {
+		t.Error("Expected default security filters on profiles")
+	}
+
+	if profiles[1].Name != "ci" || len(profiles[1].Tokens) != 1 || len(profiles[1].ClientCIDRs) != 1 {
+		t.Errorf("Unexpected second profile: %+v", profiles[1])
+	}
+	if profiles[1].AdvancedFilters != nil {
+		t.Error("Expected a profile without filters to use the global filters")
+	}
+	if len(profiles[2].UIDs) != 1 || profiles[2].UIDs[0] != 1001 || len(profiles[2].GIDs) != 1 {
+		t.Errorf("Unexpected peer credential selectors: %+v", profiles[2])
+	}
+}
+
+func TestLoadListeners(t *testing.T) {
+	os.Unsetenv("DKRPRX__DISABLE_DEFAULTS")
+	os.Setenv("SOCKET_PERMS", "0640")
+	defer os.Unsetenv("SOCKET_PERMS")
+
+	t.Run("LISTEN_SOCKET without listeners section", func(t *testing.T) {
+		listeners := loadListeners(nil, &Config{ListenAddr: ":2375", ListenSocket: "unix:///tmp/proxy.sock"})
+		if len(listeners) != 1 {
+			t.Fatalf("Expected 1 listener, got %d", len(listeners))
+		}
+		if listeners[0].Socket != "unix:///tmp/proxy.sock" || listeners[0].Name != "unix:///tmp/proxy.sock" || listeners[0].Perms != "0640" {
+			t.Errorf("Unexpected listener: %+v", listeners[0])
+		}
+		if listeners[0].AccessRules != nil || listeners[0].AdvancedFilters != nil {
+			t.Error("Expected the default listener to use the global rules")
+		}
+	})
+
+	t.Run("LISTEN_ADDR without listeners section", func(t *testing.T) {
+		listenTLS := &ListenTLS{CertFile: "/certs/server.pem"}
+		listeners := loadListeners(nil, &Config{ListenAddr: ":2376", ListenTLS: listenTLS})
+		if len(listeners) != 1 || listeners[0].Address != ":2376" || listeners[0].TLS != listenTLS {
+			t.Errorf("Unexpected listeners: %+v", listeners)
+		}
+	})
+
+	t.Run("Listeners section", func(t *testing.T) {
+		listeners := loadListeners([]*listenerFile{
+			{Name: "admin", Socket: "/run/dockershield/admin.sock", Perms: "0600", Owner: "root", Group: "docker", AccessRules: map[string]bool{"info": true, "post": true}},
+			{Socket: "/run/dockershield/monitoring.sock"},
+			{Name: "empty"},
+			{Name: "dashboard", Address: ":2376", TLS: &ListenTLS{CertFile: "/certs/server.pem"}},
+		}, &Config{ListenAddr: ":2375"})
+
+		if len(listeners) != 3 {
+			t.Fatalf("Expected 3 valid listeners, got %d", len(listeners))
+		}
+
+		admin := listeners[0]
+		if admin.Perms != "0600" || admin.Owner != "root" || admin.Group != "docker" {
+			t.Errorf("Unexpected admin socket settings: %+v", admin)
+		}
+		if admin.AccessRules == nil || !admin.AccessRules.Info || !admin.AccessRules.Post || admin.AccessRules.Containers {
+			t.Errorf("Unexpected admin rules: %+v", admin.AccessRules)
+		}
+
+		monitoring := listeners[1]
+		if monitoring.Name != "/run/dockershield/monitoring.sock" || monitoring.Perms != "0640" {
+			t.Errorf("Expected name and permissions defaults, got %+v", monitoring)
+		}
+		if monitoring.AccessRules != nil || monitoring.AdvancedFilters != nil {
+			t.Error("Expected a listener without rules to use the global rules")
+		}
+
+		if listeners[2].Address != ":2376" || listeners[2].TLS == nil {
+			t.Errorf("Unexpected dashboard listener: %+v", listeners[2])
+		}
+	})
+}
+
+func TestLoadContainerCapabilitiesFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__CONTAINERS__ALLOWED_CAPABILITIES", "NET_BIND_SERVICE,CHOWN")
+	os.Setenv("DKRPRX__CONTAINERS__DENIED_CAPABILITIES", "ALL")
+	os.Setenv("DKRPRX__CONTAINERS__REQUIRED_CAP_DROP", "NET_RAW|MKNOD")
+	defer func() {
+		os.Unsetenv("DKRPRX__CONTAINERS__ALLOWED_CAPABILITIES")
+		os.Unsetenv("DKRPRX__CONTAINERS__DENIED_CAPABILITIES")
+		os.Unsetenv("DKRPRX__CONTAINERS__REQUIRED_CAP_DROP")
+	}()
+
+	cf := loadContainerFilters()
+	if cf == nil {
+		t.Fatal("Expected container filters from environment")
+	}
+	if len(cf.AllowedCapabilities) != 2 || cf.AllowedCapabilities[1] != "CHOWN" {
+		t.Errorf("Unexpected allowed capabilities: %v", cf.AllowedCapabilities)
+	}
+	if len(cf.DeniedCapabilities) != 1 || cf.DeniedCapabilities[0] != "ALL" {
+		t.Errorf("Unexpected denied capabilities: %v", cf.DeniedCapabilities)
+	}
+	if len(cf.RequiredCapDrop) != 2 || cf.RequiredCapDrop[1] != "MKNOD" {
+		t.Errorf("Unexpected required cap drop: %v", cf.RequiredCapDrop)
+	}
+}
+
+func TestLoadContainerSecurityOptionsFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__CONTAINERS__DENY_UNCONFINED", "true")
+	os.Setenv("DKRPRX__CONTAINERS__ALLOWED_APPARMOR_PROFILES", "^docker-default$")
+	os.Setenv("DKRPRX__CONTAINERS__ALLOWED_SELINUX_LABELS", "^level:s0:c1,c2$;^type:svirt_apache_t$")
+	os.Setenv("DKRPRX__CONTAINERS__REQUIRE_NO_NEW_PRIVILEGES", "1")
+	defer func() {
+		os.Unsetenv("DKRPRX__CONTAINERS__DENY_UNCONFINED")
+		os.Unsetenv("DKRPRX__CONTAINERS__ALLOWED_APPARMOR_PROFILES")
+		os.Unsetenv("DKRPRX__CONTAINERS__ALLOWED_SELINUX_LABELS")
+		os.Unsetenv("DKRPRX__CONTAINERS__REQUIRE_NO_NEW_PRIVILEGES")
+	}()
+
+	cf := loadContainerFilters()
+	if cf == nil {
+		t.Fatal("Expected container filters from environment")
+	}
+	if !cf.DenyUnconfined || !cf.RequireNoNewPrivileges {
+		t.Errorf("Expected deny_unconfined and require_no_new_privileges, got %v and %v", cf.DenyUnconfined, cf.RequireNoNewPrivileges)
+	}
+	if len(cf.AllowedAppArmorProfiles) != 1 {
+		t.Errorf("Unexpected AppArmor profiles: %v", cf.AllowedAppArmorProfiles)
+	}
+	if len(cf.AllowedSELinuxLabels) != 2 || cf.AllowedSELinuxLabels[0] != "^level:s0:c1,c2$" {
+		t.Errorf("Unexpected SELinux labels: %v", cf.AllowedSELinuxLabels)
+	}
+}
+
+func TestLoadContainerResourcesFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__CONTAINERS__RESOURCES__MAX_MEMORY", "2g")
+	os.Setenv("DKRPRX__CONTAINERS__RESOURCES__MAX_CPUS", "1.5")
+	os.Setenv("DKRPRX__CONTAINERS__RESOURCES__REQUIRE_PIDS_LIMIT", "true")
+	os.Setenv("DKRPRX__CONTAINERS__RESOURCES__MAX_PIDS_LIMIT", "invalid")
+	defer func() {
+		os.Unsetenv("DKRPRX__CONTAINERS__RESOURCES__MAX_MEMORY")
+		os.Unsetenv("DKRPRX__CONTAINERS__RESOURCES__MAX_CPUS")
+		os.Unsetenv("DKRPRX__CONTAINERS__RESOURCES__REQUIRE_PIDS_LIMIT")
+		os.Unsetenv("DKRPRX__CONTAINERS__RESOURCES__MAX_PIDS_LIMIT")
+	}()
+
+	cf := loadContainerFilters()
+	if cf == nil || cf.Resources == nil {
+		t.Fatal("Expected resource filters from environment")
+	}
+	rf := cf.Resources
+	if rf.MaxMemory != "2g" || rf.MaxCPUs != 1.5 || !rf.RequirePidsLimit {
+		t.Errorf("Unexpected resource filters: %+v", rf)
+	}
+	if rf.MaxPidsLimit != 0 {
+		t.Errorf("Expected invalid PIDs maximum to be ignored, got %d", rf.MaxPidsLimit)
+	}
+}
+
+func TestLoadContainerPortsFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__CONTAINERS__PORTS__DENY_PUBLISH_ALL", "true")
+	os.Setenv("DKRPRX__CONTAINERS__PORTS__ALLOWED_HOST_PORTS", "8000-8999,9443")
+	os.Setenv("DKRPRX__CONTAINERS__PORTS__ALLOWED_HOST_IPS", "127.0.0.1")
+	defer func() {
+		os.Unsetenv("DKRPRX__CONTAINERS__PORTS__DENY_PUBLISH_ALL")
+		os.Unsetenv("DKRPRX__CONTAINERS__PORTS__ALLOWED_HOST_PORTS")
+		os.Unsetenv("DKRPRX__CONTAINERS__PORTS__ALLOWED_HOST_IPS")
+	}()
+
+	cf := loadContainerFilters()
+	if cf == nil || cf.Ports == nil {
+		t.Fatal("Expected port filters from environment")
+	}
+	pf := cf.Ports
+	if !pf.DenyPublishAll || pf.DenyPrivilegedPorts {
+		t.Errorf("Unexpected port switches: %+v", pf)
+	}
+	if len(pf.AllowedHostPorts) != 2 || pf.AllowedHostPorts[0] != "8000-8999" {
+		t.Errorf("Unexpected allowed host ports: %v", pf.AllowedHostPorts)
+	}
+	if len(pf.AllowedHostIPs) != 1 || pf.AllowedHostIPs[0] != "127.0.0.1" {
+		t.Errorf("Unexpected allowed host IPs: %v", pf.AllowedHostIPs)
+	}
+}
+
+func TestLoadContainerUserFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__CONTAINERS__USER__DENY_ROOT", "true")
+	os.Setenv("DKRPRX__CONTAINERS__USER__ALLOWED_UIDS", "1000-1999,65534")
+	os.Setenv("DKRPRX__CONTAINERS__USER__INSPECT_IMAGE", "yes")
+	defer func() {
+		os.Unsetenv("DKRPRX__CONTAINERS__USER__DENY_ROOT")
+		os.Unsetenv("DKRPRX__CONTAINERS__USER__ALLOWED_UIDS")
+		os.Unsetenv("DKRPRX__CONTAINERS__USER__INSPECT_IMAGE")
+	}()
+
+	cf := loadContainerFilters()
+	if cf == nil || cf.User == nil {
+		t.Fatal("Expected user filters from environment")
+	}
+	uf := cf.User
+	if !uf.DenyRoot || !uf.InspectImage {
+		t.Errorf("Unexpected user switches: %+v", uf)
+	}
+	if len(uf.AllowedUIDs) != 2 || uf.AllowedUIDs[1] != "65534" {
+		t.Errorf("Unexpected allowed UIDs: %v", uf.AllowedUIDs)
+	}
+	if len(uf.AllowedGIDs) != 0 {
+		t.Errorf("Expected no allowed GIDs, got %v", uf.AllowedGIDs)
+	}
+}
+
+func TestLoadContainerEnvRulesFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__CONTAINERS__DENIED_ENV", "^LD_PRELOAD$,^LD_LIBRARY_PATH$")
+	os.Setenv("DKRPRX__CONTAINERS__DENIED_ENV_VALUES", "^AKIA[0-9A-Z]{16}$;^ghp_")
+	defer func() {
+		os.Unsetenv("DKRPRX__CONTAINERS__DENIED_ENV")
+		os.Unsetenv("DKRPRX__CONTAINERS__DENIED_ENV_VALUES")
+	}()
+
+	cf := loadContainerFilters()
+	if cf == nil {
+		t.Fatal("Expected container filters from environment")
+	}
+	if len(cf.DeniedEnv) != 2 || cf.DeniedEnv[0] != "^LD_PRELOAD$" {
+		t.Errorf("Unexpected denied variables: %v", cf.DeniedEnv)
+	}
+	if len(cf.DeniedEnvValues) != 2 || cf.DeniedEnvValues[0] != "^AKIA[0-9A-Z]{16}$" {
+		t.Errorf("Unexpected denied values: %v", cf.DeniedEnvValues)
+	}
+	if len(cf.AllowedEnv) != 0 {
+		t.Errorf("Expected no allowed variables, got %v", cf.AllowedEnv)
+	}
+}
+
+func TestLoadLabelFiltersFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__CONTAINERS__LABELS__REQUIRED", "team=^[a-z]+$")
+	os.Setenv("DKRPRX__CONTAINERS__LABELS__DENIED_KEYS", "^traefik\\.")
+	os.Setenv("DKRPRX__VOLUMES__LABELS__ALLOWED_KEYS", "^backup$,^team$")
+	os.Setenv("DKRPRX__NETWORKS__LABELS__ALLOWED_VALUES", "com.docker.compose.project=^ci-")
+	defer func() {
+		os.Unsetenv("DKRPRX__CONTAINERS__LABELS__REQUIRED")
+		os.Unsetenv("DKRPRX__CONTAINERS__LABELS__DENIED_KEYS")
+		os.Unsetenv("DKRPRX__VOLUMES__LABELS__ALLOWED_KEYS")
+		os.Unsetenv("DKRPRX__NETWORKS__LABELS__ALLOWED_VALUES")
+	}()
+
+	filter := LoadFiltersFromEnv()
+	if filter == nil || filter.Containers == nil || filter.Volumes == nil || filter.Networks == nil {
+		t.Fatal("Expected container, volume and network filters from environment")
+	}
+
+	containerLabels := filter.Containers.Labels
+	if containerLabels == nil || containerLabels.Required["team"] != "^[a-z]+$" {
+		t.Errorf("Unexpected container label rules: %+v", containerLabels)
+	}
+	if len(containerLabels.DeniedKeys) != 1 || containerLabels.DeniedKeys[0] != `^traefik\.` {
+		t.Errorf("Unexpected denied label keys: %v", containerLabels.DeniedKeys)
+	}
+	if volumeLabels := filter.Volumes.Labels; volumeLabels == nil || len(volumeLabels.AllowedKeys) != 2 {
+		t.Errorf("Unexpected volume label rules: %+v", volumeLabels)
+	}
+	if networkLabels := filter.Networks.Labels; networkLabels == nil || networkLabels.AllowedValues["com.docker.compose.project"] != "^ci-" {
+		t.Errorf("Unexpected network label rules: %+v", networkLabels)
+	}
+}
+
+func TestLoadContainerOperationFiltersFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__UPDATES__RESOURCES__MAX_MEMORY", "4g")
+	os.Setenv("DKRPRX__RESTART_POLICY__ALLOWED_POLICIES", "no,on-failure")
+	os.Setenv("DKRPRX__RESTART_POLICY__MAX_RETRIES", "5")
+	os.Setenv("DKRPRX__EXEC__DENY_PRIVILEGED", "true")
+	os.Setenv("DKRPRX__RENAME__DENIED_NAMES", "^dockershield$")
+	os.Setenv("DKRPRX__COMMIT__DENY_ALL", "true")
+	defer func() {
+		os.Unsetenv("DKRPRX__UPDATES__RESOURCES__MAX_MEMORY")
+		os.Unsetenv("DKRPRX__RESTART_POLICY__ALLOWED_POLICIES")
+		os.Unsetenv("DKRPRX__RESTART_POLICY__MAX_RETRIES")
+		os.Unsetenv("DKRPRX__EXEC__DENY_PRIVILEGED")
+		os.Unsetenv("DKRPRX__RENAME__DENIED_NAMES")
+		os.Unsetenv("DKRPRX__COMMIT__DENY_ALL")
+	}()
+
+	filter := LoadFiltersFromEnv()
+	if filter == nil {
+		t.Fatal("Expected filters from environment")
+	}
+	if filter.Updates == nil || filter.Updates.Resources == nil || filter.Updates.Resources.MaxMemory != "4g" {
+		t.Errorf("Unexpected update filters: %+v", filter.Updates)
+	}
+	if filter.RestartPolicy == nil || len(filter.RestartPolicy.AllowedPolicies) != 2 || filter.RestartPolicy.MaxRetries != 5 {
+		t.Errorf("Unexpected restart policy filters: %+v", filter.RestartPolicy)
+	}
+	if filter.Exec == nil || !filter.Exec.DenyPrivileged || filter.Exec.DenyRoot {
+		t.Errorf("Unexpected exec filters: %+v", filter.Exec)
+	}
+	if filter.Rename == nil || len(filter.Rename.DeniedNames) != 1 {
+		t.Errorf("Unexpected rename filters: %+v", filter.Rename)
+	}
+	if filter.Commit == nil || !filter.Commit.DenyAll {
+		t.Errorf("Unexpected commit filters: %+v", filter.Commit)
+	}
+	if filter.Containers != nil {
+		t.Errorf("Expected no container filters, got %+v", filter.Containers)
+	}
+
+	merged := MergeFilters(&filters.AdvancedFilter{Exec: &filters.ExecFilter{DenyRoot: true}, Rename: &filters.RenameFilter{DenyAll: true}},
+		&filters.AdvancedFilter{Exec: filter.Exec})
+	if merged.Exec != filter.Exec || merged.Rename == nil || !merged.Rename.DenyAll {
+		t.Errorf("Unexpected merged filters: exec=%+v rename=%+v", merged.Exec, merged.Rename)
+	}
+}
+
+func TestLoadOwnershipFiltersFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__OWNERSHIP__LABELS", "com.example.owner=ci,com.example.stack=build")
+	defer os.Unsetenv("DKRPRX__OWNERSHIP__LABELS")
+
+	filter := LoadFiltersFromEnv()
+	if filter == nil || filter.Ownership == nil {
+		t.Fatal("Expected ownership filters from environment")
+	}
+	if len(filter.Ownership.Labels) != 2 || filter.Ownership.Labels["com.example.owner"] != "ci" {
+		t.Errorf("Unexpected ownership labels: %v", filter.Ownership.Labels)
+	}
+
+	merged := MergeFilters(&filters.AdvancedFilter{Ownership: &filters.OwnershipFilter{Labels: map[string]string{"owner": "json"}}}, filter)
+	if merged.Ownership != filter.Ownership {
+		t.Errorf("Expected the environment ownership labels to win, got %v", merged.Ownership.Labels)
+	}
+}
+
+func TestLoadVisibilityFiltersFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__VISIBILITY__LABELS", "com.docker.compose.project=^shop$")
+	os.Setenv("DKRPRX__VISIBILITY__NAMES", "^shop-;^registry\\.company\\.com/shop/")
+	defer func() {
+		os.Unsetenv("DKRPRX__VISIBILITY__LABELS")
+		os.Unsetenv("DKRPRX__VISIBILITY__NAMES")
+	}()
+
+	filter := LoadFiltersFromEnv()
+	if filter == nil || filter.Visibility == nil {
+		t.Fatal("Expected visibility filters from environment")
+	}
+	if filter.Visibility.Labels["com.docker.compose.project"] != "^shop$" {
+		t.Errorf("Unexpected visibility labels: %v", filter.Visibility.Labels)
+	}
+	if len(filter.Visibility.Names) != 2 {
+		t.Errorf("Unexpected visibility names: %v", filter.Visibility.Names)
+	}
+
+	merged := MergeFilters(&filters.AdvancedFilter{Visibility: &filters.VisibilityFilter{Names: []string{"^web$"}}},
+		&filters.AdvancedFilter{Ownership: &filters.OwnershipFilter{Labels: map[string]string{"owner": "shop"}}})
+	if merged.Visibility == nil || merged.Visibility.Names[0] != "^web$" || merged.Ownership == nil {
+		t.Errorf("Unexpected merged filters: visibility=%+v ownership=%+v", merged.Visibility, merged.Ownership)
+	}
+}
+
+func TestLoadMutationFiltersFromEnv(t *testing.T) {
+	os.Setenv("DKRPRX__MUTATIONS__CONTAINERS__LABELS", "com.example.owner=ci")
+	os.Setenv("DKRPRX__MUTATIONS__CONTAINERS__SECURITY_OPT", "no-new-privileges")
+	os.Setenv("DKRPRX__MUTATIONS__CONTAINERS__DEFAULT_MEMORY", "512m")
+	os.Setenv("DKRPRX__MUTATIONS__CONTAINERS__DEFAULT_PIDS_LIMIT", "256")
+	os.Setenv("DKRPRX__MUTATIONS__CONTAINERS__INIT", "true")
+	os.Setenv("DKRPRX__MUTATIONS__VOLUMES__LABELS", "com.example.owner=ci")
+	defer func() {
+		os.Unsetenv("DKRPRX__MUTATIONS__CONTAINERS__LABELS")
+		os.Unsetenv("DKRPRX__MUTATIONS__CONTAINERS__SECURITY_OPT")
+		os.Unsetenv("DKRPRX__MUTATIONS__CONTAINERS__DEFAULT_MEMORY")
+		os.Unsetenv("DKRPRX__MUTATIONS__CONTAINERS__DEFAULT_PIDS_LIMIT")
+		os.Unsetenv("DKRPRX__MUTATIONS__CONTAINERS__INIT")
+		os.Unsetenv("DKRPRX__MUTATIONS__VOLUMES__LABELS")
+	}()
+
+	filter := LoadFiltersFromEnv()
+	if filter == nil || filter.Mutations == nil {
+		t.Fatal("Expected mutations from environment")
+	}
+
+	cm := filter.Mutations.Containers
+	if cm == nil || cm.Labels["com.example.owner"] != "ci" || len(cm.SecurityOpt) != 1 || cm.DefaultMemory != "512m" ||
+		cm.DefaultPidsLimit != 256 || !cm.Init || cm.ReadonlyRootfs {
+		t.Errorf("Unexpected container mutations: %+v", cm)
+	}
+	if filter.Mutations.Volumes == nil || filter.Mutations.Volumes.Labels["com.example.owner"] != "ci" {
+		t.Errorf("Unexpected volume mutations: %+v", filter.Mutations.Volumes)
+	}
+	if filter.Mutations.Networks != nil {
+		t.Errorf("Expected no network mutations, got %+v", filter.Mutations.Networks)
+	}
+}
+
+func TestDetectOwnContainerID(t *testing.T) {
+	const id = "3f4e1b2c9d8a7f6e5d4c3b2a1908f7e6d5c4b3a29180f7e6d5c4b3a291807f6e"
+
+	tests := []struct {
+		name      string
+		mountinfo string
+		expected  string
+	}{
+		{
+			name:      "Docker container",
+			mountinfo: "621 600 8:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw,relatime - ext4 /dev/sda1 rw\n",
+			expected:  id,
+		},
+		{
+			name:      "Podman container",
+			mountinfo: "412 398 0:44 /containers/storage/overlay-containers/" + id + "/userdata/hostname /etc/hostname rw - tmpfs tmpfs rw\n",
+			expected:  id,
+		},
+		{
+			name:      "Not in a container",
+			mountinfo: "22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw\n",
+			expected:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "mountinfo")
+			if err := os.WriteFile(path, []byte(tt.mountinfo), 0600); err != nil {
+				t.Fatalf("Failed to write mountinfo: %v", err)
+			}
+			if result := detectOwnContainerID(path); result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+
+	if result := detectOwnContainerID(filepath.Join(t.TempDir(), "missing")); result != "" {
+		t.Errorf("Expected no ID without mountinfo, got '%s'", result)
+	}
+}
+
+func TestApplyDefaultsKeepsProxyProtection(t *testing.T) {
+	os.Setenv("PROXY_CONTAINER_NAME", "shield")
+	defer os.Unsetenv("PROXY_CONTAINER_NAME")
+
+	filter := ApplyDefaults(&filters.AdvancedFilter{
+		Containers: &filters.ContainerFilter{AllowedImages: []string{"^nginx"}},
+	})
+	if len(filter.Containers.ProtectedContainers) == 0 || filter.Containers.ProtectedContainers[0] != "shield" {
+		t.Errorf("Expected the proxy container to stay protected, got %v", filter.Containers.ProtectedContainers)
+	}
+
+	custom := ApplyDefaults(&filters.AdvancedFilter{
+		Containers: &filters.ContainerFilter{ProtectedContainers: []string{"vault"}},
+	})
+	if len(custom.Containers.ProtectedContainers) != 1 || custom.Containers.ProtectedContainers[0] != "vault" {
+		t.Errorf("Expected configured protected containers to be kept, got %v", custom.Containers.ProtectedContainers)
+	}
+}
+
+func TestLoadDockerTLS(t *testing.T) {
+	vars := []string{
+		"DOCKER_CERT_PATH", "DOCKER_TLS_VERIFY", "DOCKER_TLS_CA",
+		"DOCKER_TLS_CERT", "DOCKER_TLS_KEY", "DOCKER_TLS_SERVER_NAME",
+	}
+	cleanEnv := func() {
+		for _, v := range vars {
+			os.Unsetenv(v)
+		}
+	}
+
+	t.Run("Disabled by default", func(t *testing.T) {
+		cleanEnv()
+		if dockerTLS := loadDockerTLS(); dockerTLS != nil {
+			t.Errorf("Expected no TLS configuration, got %+v", dockerTLS)
+		}
+	})
+
+	t.Run("DOCKER_CERT_PATH without DOCKER_TLS_VERIFY", func(t *testing.T) {
+		cleanEnv()
+		defer cleanEnv()
+		os.Setenv("DOCKER_CERT_PATH", "/certs")
+
+		dockerTLS := loadDockerTLS()
+		if dockerTLS == nil {
+			t.Fatal("Expected TLS configuration")
+		}
+		if dockerTLS.CAFile != "/certs/ca.pem" || dockerTLS.CertFile != "/certs/cert.pem" || dockerTLS.KeyFile != "/certs/key.pem" {
+			t.Errorf("Unexpected certificate files: %+v", dockerTLS)
+		}
+		if !dockerTLS.InsecureSkipVerify {
+			t.Error("Expected daemon certificate not to be verified without DOCKER_TLS_VERIFY")
+		}
+	})
+
+	t.Run("DOCKER_TLS_VERIFY with explicit files", func(t *testing.T) {
+		cleanEnv()
+		defer cleanEnv()
+		os.Setenv("DOCKER_CERT_PATH", "/certs")
+		os.Setenv("DOCKER_TLS_VERIFY", "1")
+		os.Setenv("DOCKER_TLS_CA", "/etc/docker/ca.pem")
+		os.Setenv("DOCKER_TLS_SERVER_NAME", "docker.internal")
+
+		dockerTLS := loadDockerTLS()
+		if dockerTLS == nil {
+			t.Fatal("Expected TLS configuration")
+		}
+		if dockerTLS.CAFile != "/etc/docker/ca.pem" {
+			t.Errorf("Expected explicit CA file, got '%s'", dockerTLS.CAFile)
+		}
+		if dockerTLS.CertFile != "/certs/cert.pem" {
+			t.Errorf("Expected client certificate from DOCKER_CERT_PATH, got '%s'", dockerTLS.CertFile)
+		}
+		if dockerTLS.ServerName != "docker.internal" || dockerTLS.InsecureSkipVerify {
+			t.Errorf("Expected verified server name, got %+v", dockerTLS)
+		}
+	})
+}
+
+func TestLoadListenTLS(t *testing.T) {
+	vars := []string{"LISTEN_TLS_CERT", "LISTEN_TLS_KEY", "LISTEN_TLS_CLIENT_CA", "LISTEN_TLS_CLIENT_AUTH"}
+	defer func() {
+		for _, v := range vars {
+			os.Unsetenv(v)
+		}
+	}()
+
+	if listenTLS := loadListenTLS(); listenTLS != nil {
+		t.Errorf("Expected TLS listener to be disabled by default, got %+v", listenTLS)
+	}
+
+	os.Setenv("LISTEN_TLS_CERT", "/certs/server.pem")
+	os.Setenv("LISTEN_TLS_KEY", "/certs/server-key.pem")
+	os.Setenv("LISTEN_TLS_CLIENT_CA", "/certs/ca.pem")
+	os.Setenv("LISTEN_TLS_CLIENT_AUTH", "Optional")
+
+	listenTLS := loadListenTLS()
+	if listenTLS == nil {
+		t.Fatal("Expected TLS listener configuration")
+	}
+	if listenTLS.CertFile != "/certs/server.pem" || listenTLS.KeyFile != "/certs/server-key.pem" || listenTLS.ClientCAFile != "/certs/ca.pem" {
+		t.Errorf("Unexpected certificate files: %+v", listenTLS)
+	}
+	if listenTLS.ClientAuth != ClientAuthOptional {
+		t.Errorf("Expected client auth '%s', got '%s'", ClientAuthOptional, listenTLS.ClientAuth)
+	}
+}
+
+// writeTestCertificate writes a self-signed server certificate and its key
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "listener"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, "listener.pem")
+	keyFile := filepath.Join(dir, "listener-key.pem")
+	for file, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	return certFile, keyFile
+}
+
+func TestListenTLSServerConfigErrors(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+
+	if _, err := (&ListenTLS{CertFile: certFile, KeyFile: keyFile}).ServerConfig(); err != nil {
+		t.Fatalf("Expected a valid configuration, got: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		listenTLS *ListenTLS
+	}{
+		{"Missing key", &ListenTLS{CertFile: certFile}},
+		{"Client auth without CA", &ListenTLS{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire}},
+		{"Unknown client auth", &ListenTLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile, ClientAuth: "maybe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.listenTLS.ServerConfig(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
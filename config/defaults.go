@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"regexp"
 
 	"dockershield/pkg/filters"
 )
@@ -18,13 +19,19 @@ func GetDefaultFilters() *filters.AdvancedFilter {
 			`^` + proxyContainerName + `$`,
 			`^/` + proxyContainerName + `$`,
 		},
+		// Interdire de rejoindre ses namespaces (--network/--pid/--ipc container:dockershield)
+		ProtectedContainers: proxyContainers(proxyContainerName),
 	}
 
 	volumeFilter := &filters.VolumeFilter{
-		// Par défaut, interdire le montage du socket Docker
+		// Par défaut, interdire le montage du socket Docker, y compris via
+		// un répertoire parent (-v /:/host, -v /var/run:/var/run)
 		DeniedPaths: []string{
 			`^/var/run/docker\.sock$`,
 			`^/run/docker\.sock$`,
+			`^/$`,
+			`^/var$`,
+			`^/(var/)?run$`,
 		},
 	}
 
@@ -59,6 +66,9 @@ func ApplyDefaults(filter *filters.AdvancedFilter) *filters.AdvancedFilter {
 
 	if filter.Containers == nil {
 		filter.Containers = defaults.Containers
+	} else if len(filter.Containers.ProtectedContainers) == 0 {
+		// Le conteneur du proxy reste protégé même avec des filtres de conteneurs personnalisés
+		filter.Containers.ProtectedContainers = defaults.Containers.ProtectedContainers
 	}
 
 	if filter.Networks == nil && defaults.Networks != nil && len(defaults.Networks.DeniedNames) > 0 {
@@ -77,3 +87,28 @@ func CanOverrideDefaults() bool {
 	}
 	return parseBool(val)
 }
+
+// containerIDPattern trouve l'ID du conteneur dans les points de montage
+// (/var/lib/docker/containers/<id>/hostname, overlay-containers/<id>/ pour Podman)
+var containerIDPattern = regexp.MustCompile(`containers/([0-9a-f]{64})/`)
+
+// proxyContainers retourne le nom et, s'il est détecté, l'ID du conteneur du proxy
+func proxyContainers(proxyContainerName string) []string {
+	containers := []string{proxyContainerName}
+	if id := detectOwnContainerID("/proc/self/mountinfo"); id != "" {
+		containers = append(containers, id)
+	}
+	return containers
+}
+
+// detectOwnContainerID lit l'ID du conteneur courant, ou "" hors conteneur
+func detectOwnContainerID(mountinfoPath string) string {
+	data, err := os.ReadFile(mountinfoPath)
+	if err != nil {
+		return ""
+	}
+	if match := containerIDPattern.FindSubmatch(data); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
 
 	"dockershield/pkg/filters"
@@ -39,6 +40,47 @@ func LoadFiltersFromEnv() *filters.AdvancedFilter {
 		hasAnyFilter = true
 	}
 
+	// Opérations sur les conteneurs existants
+	if uf := loadUpdateFilters(); uf != nil {
+		filter.Updates = uf
+		hasAnyFilter = true
+	}
+
+	if rp := loadRestartPolicyFilters(); rp != nil {
+		filter.RestartPolicy = rp
+		hasAnyFilter = true
+	}
+
+	if ef := loadExecFilters(); ef != nil {
+		filter.Exec = ef
+		hasAnyFilter = true
+	}
+
+	if rf := loadRenameFilters(); rf != nil {
+		filter.Rename = rf
+		hasAnyFilter = true
+	}
+
+	if cmf := loadCommitFilters(); cmf != nil {
+		filter.Commit = cmf
+		hasAnyFilter = true
+	}
+
+	if of := loadOwnershipFilters(); of != nil {
+		filter.Ownership = of
+		hasAnyFilter = true
+	}
+
+	if vf := loadVisibilityFilters(); vf != nil {
+		filter.Visibility = vf
+		hasAnyFilter = true
+	}
+
+	if mf := loadMutationFilters(); mf != nil {
+		filter.Mutations = mf
+		hasAnyFilter = true
+	}
+
 	if !hasAnyFilter {
 		return nil
 	}
@@ -76,6 +118,11 @@ func loadVolumeFilters() *filters.VolumeFilter {
 		hasFilter = true
 	}
 
+	if lf := loadLabelFilters("VOLUMES"); lf != nil {
+		vf.Labels = lf
+		hasFilter = true
+	}
+
 	if !hasFilter {
 		return nil
 	}
@@ -112,6 +159,26 @@ func loadContainerFilters() *filters.ContainerFilter {
 		hasFilter = true
 	}
 
+	if lf := loadLabelFilters("CONTAINERS"); lf != nil {
+		cf.Labels = lf
+		hasFilter = true
+	}
+
+	if allowedEnv := getEnvArray("CONTAINERS__ALLOWED_ENV"); len(allowedEnv) > 0 {
+		cf.AllowedEnv = allowedEnv
+		hasFilter = true
+	}
+
+	if deniedEnv := getEnvArray("CONTAINERS__DENIED_ENV"); len(deniedEnv) > 0 {
+		cf.DeniedEnv = deniedEnv
+		hasFilter = true
+	}
+
+	if deniedValues := getEnvArray("CONTAINERS__DENIED_ENV_VALUES"); len(deniedValues) > 0 {
+		cf.DeniedEnvValues = deniedValues
+		hasFilter = true
+	}
+
 	if val := os.Getenv(envPrefix + "CONTAINERS__DENY_PRIVILEGED"); val != "" {
 		cf.DenyPrivileged = parseBool(val)
 		hasFilter = true
@@ -122,12 +189,481 @@ func loadContainerFilters() *filters.ContainerFilter {
 		hasFilter = true
 	}
 
+	if allowedCaps := getEnvArray("CONTAINERS__ALLOWED_CAPABILITIES"); len(allowedCaps) > 0 {
+		cf.AllowedCapabilities = allowedCaps
+		hasFilter = true
+	}
+
+	if deniedCaps := getEnvArray("CONTAINERS__DENIED_CAPABILITIES"); len(deniedCaps) > 0 {
+		cf.DeniedCapabilities = deniedCaps
+		hasFilter = true
+	}
+
+	if requiredCapDrop := getEnvArray("CONTAINERS__REQUIRED_CAP_DROP"); len(requiredCapDrop) > 0 {
+		cf.RequiredCapDrop = requiredCapDrop
+		hasFilter = true
+	}
+
+	namespaceSwitches := []struct {
+		key    string
+		target *bool
+	}{
+		{"CONTAINERS__DENY_HOST_PID", &cf.DenyHostPID},
+		{"CONTAINERS__DENY_HOST_IPC", &cf.DenyHostIPC},
+		{"CONTAINERS__DENY_HOST_UTS", &cf.DenyHostUTS},
+		{"CONTAINERS__DENY_HOST_USERNS", &cf.DenyHostUserns},
+		{"CONTAINERS__DENY_HOST_CGROUPNS", &cf.DenyHostCgroupns},
+		{"CONTAINERS__DENY_CONTAINER_NAMESPACES", &cf.DenyContainerNamespaces},
+	}
+	for _, sw := range namespaceSwitches {
+		if val := os.Getenv(envPrefix + sw.key); val != "" {
+			*sw.target = parseBool(val)
+			hasFilter = true
+		}
+	}
+
+	if protected := getEnvArray("CONTAINERS__PROTECTED_CONTAINERS"); len(protected) > 0 {
+		cf.ProtectedContainers = protected
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "CONTAINERS__DENY_UNCONFINED"); val != "" {
+		cf.DenyUnconfined = parseBool(val)
+		hasFilter = true
+	}
+
+	if seccompProfiles := getEnvArray("CONTAINERS__ALLOWED_SECCOMP_PROFILES"); len(seccompProfiles) > 0 {
+		cf.AllowedSeccompProfiles = seccompProfiles
+		hasFilter = true
+	}
+
+	if apparmorProfiles := getEnvArray("CONTAINERS__ALLOWED_APPARMOR_PROFILES"); len(apparmorProfiles) > 0 {
+		cf.AllowedAppArmorProfiles = apparmorProfiles
+		hasFilter = true
+	}
+
+	// Les niveaux SELinux contiennent des virgules (s0:c1,c2) : utiliser ";" ou "|" comme séparateur
+	if selinuxLabels := getEnvArray("CONTAINERS__ALLOWED_SELINUX_LABELS"); len(selinuxLabels) > 0 {
+		cf.AllowedSELinuxLabels = selinuxLabels
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "CONTAINERS__REQUIRE_NO_NEW_PRIVILEGES"); val != "" {
+		cf.RequireNoNewPrivileges = parseBool(val)
+		hasFilter = true
+	}
+
+	if df := loadDeviceFilters(); df != nil {
+		cf.Devices = df
+		hasFilter = true
+	}
+
+	if rf := loadResourceFilters("CONTAINERS__RESOURCES"); rf != nil {
+		cf.Resources = rf
+		hasFilter = true
+	}
+
+	if pf := loadPortFilters(); pf != nil {
+		cf.Ports = pf
+		hasFilter = true
+	}
+
+	if uf := loadUserFilters(); uf != nil {
+		cf.User = uf
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return cf
+}
+
+// loadDeviceFilters loads container device filters from environment
+func loadDeviceFilters() *filters.DeviceFilter {
+	df := &filters.DeviceFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + "CONTAINERS__DEVICES__DENY_ALL"); val != "" {
+		df.DenyAll = parseBool(val)
+		hasFilter = true
+	}
+
+	if allowedPaths := getEnvArray("CONTAINERS__DEVICES__ALLOWED_PATHS"); len(allowedPaths) > 0 {
+		df.AllowedPaths = allowedPaths
+		hasFilter = true
+	}
+
+	if deniedPaths := getEnvArray("CONTAINERS__DEVICES__DENIED_PATHS"); len(deniedPaths) > 0 {
+		df.DeniedPaths = deniedPaths
+		hasFilter = true
+	}
+
+	if allowedRules := getEnvArray("CONTAINERS__DEVICES__ALLOWED_CGROUP_RULES"); len(allowedRules) > 0 {
+		df.AllowedCgroupRules = allowedRules
+		hasFilter = true
+	}
+
+	if allowedDrivers := getEnvArray("CONTAINERS__DEVICES__ALLOWED_DRIVERS"); len(allowedDrivers) > 0 {
+		df.AllowedDrivers = allowedDrivers
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return df
+}
+
+// loadResourceFilters loads resource limits from environment (CONTAINERS__RESOURCES or UPDATES__RESOURCES)
+func loadResourceFilters(section string) *filters.ResourceFilter {
+	rf := &filters.ResourceFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + section + "__REQUIRE_MEMORY"); val != "" {
+		rf.RequireMemory = parseBool(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + section + "__MAX_MEMORY"); val != "" {
+		rf.MaxMemory = strings.TrimSpace(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + section + "__REQUIRE_CPU"); val != "" {
+		rf.RequireCPU = parseBool(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + section + "__MAX_CPUS"); val != "" {
+		if cpus, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil && cpus > 0 {
+			rf.MaxCPUs = cpus
+			hasFilter = true
+		}
+	}
+
+	if val := os.Getenv(envPrefix + section + "__REQUIRE_PIDS_LIMIT"); val != "" {
+		rf.RequirePidsLimit = parseBool(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + section + "__MAX_PIDS_LIMIT"); val != "" {
+		if pids, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64); err == nil && pids > 0 {
+			rf.MaxPidsLimit = pids
+			hasFilter = true
+		}
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return rf
+}
+
+// loadPortFilters loads container published-port filters from environment
+func loadPortFilters() *filters.PortFilter {
+	pf := &filters.PortFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + "CONTAINERS__PORTS__DENY_PUBLISH_ALL"); val != "" {
+		pf.DenyPublishAll = parseBool(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "CONTAINERS__PORTS__DENY_PRIVILEGED_PORTS"); val != "" {
+		pf.DenyPrivilegedPorts = parseBool(val)
+		hasFilter = true
+	}
+
+	if allowedPorts := getEnvArray("CONTAINERS__PORTS__ALLOWED_HOST_PORTS"); len(allowedPorts) > 0 {
+		pf.AllowedHostPorts = allowedPorts
+		hasFilter = true
+	}
+
+	if allowedIPs := getEnvArray("CONTAINERS__PORTS__ALLOWED_HOST_IPS"); len(allowedIPs) > 0 {
+		pf.AllowedHostIPs = allowedIPs
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return pf
+}
+
+// loadUserFilters loads container user filters from environment
+func loadUserFilters() *filters.UserFilter {
+	uf := &filters.UserFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + "CONTAINERS__USER__DENY_ROOT"); val != "" {
+		uf.DenyRoot = parseBool(val)
+		hasFilter = true
+	}
+
+	if allowedUIDs := getEnvArray("CONTAINERS__USER__ALLOWED_UIDS"); len(allowedUIDs) > 0 {
+		uf.AllowedUIDs = allowedUIDs
+		hasFilter = true
+	}
+
+	if allowedGIDs := getEnvArray("CONTAINERS__USER__ALLOWED_GIDS"); len(allowedGIDs) > 0 {
+		uf.AllowedGIDs = allowedGIDs
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "CONTAINERS__USER__INSPECT_IMAGE"); val != "" {
+		uf.InspectImage = parseBool(val)
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return uf
+}
+
+// loadLabelFilters charge les règles de labels d'une section (VOLUMES, CONTAINERS, NETWORKS)
+func loadLabelFilters(section string) *filters.LabelFilter {
+	lf := &filters.LabelFilter{}
+	hasFilter := false
+
+	if required := getEnvMap(section + "__LABELS__REQUIRED"); len(required) > 0 {
+		lf.Required = required
+		hasFilter = true
+	}
+
+	if allowedValues := getEnvMap(section + "__LABELS__ALLOWED_VALUES"); len(allowedValues) > 0 {
+		lf.AllowedValues = allowedValues
+		hasFilter = true
+	}
+
+	if deniedKeys := getEnvArray(section + "__LABELS__DENIED_KEYS"); len(deniedKeys) > 0 {
+		lf.DeniedKeys = deniedKeys
+		hasFilter = true
+	}
+
+	if allowedKeys := getEnvArray(section + "__LABELS__ALLOWED_KEYS"); len(allowedKeys) > 0 {
+		lf.AllowedKeys = allowedKeys
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return lf
+}
+
+// loadUpdateFilters loads container update filters from environment
+func loadUpdateFilters() *filters.UpdateFilter {
+	uf := &filters.UpdateFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + "UPDATES__DENY_ALL"); val != "" {
+		uf.DenyAll = parseBool(val)
+		hasFilter = true
+	}
+
+	if rf := loadResourceFilters("UPDATES__RESOURCES"); rf != nil {
+		uf.Resources = rf
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return uf
+}
+
+// loadRestartPolicyFilters loads restart policy filters from environment
+func loadRestartPolicyFilters() *filters.RestartPolicyFilter {
+	rp := &filters.RestartPolicyFilter{}
+	hasFilter := false
+
+	if allowedPolicies := getEnvArray("RESTART_POLICY__ALLOWED_POLICIES"); len(allowedPolicies) > 0 {
+		rp.AllowedPolicies = allowedPolicies
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "RESTART_POLICY__MAX_RETRIES"); val != "" {
+		if retries, err := strconv.Atoi(strings.TrimSpace(val)); err == nil && retries > 0 {
+			rp.MaxRetries = retries
+			hasFilter = true
+		}
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return rp
+}
+
+// loadExecFilters loads exec filters from environment
+func loadExecFilters() *filters.ExecFilter {
+	ef := &filters.ExecFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + "EXEC__DENY_PRIVILEGED"); val != "" {
+		ef.DenyPrivileged = parseBool(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "EXEC__DENY_ROOT"); val != "" {
+		ef.DenyRoot = parseBool(val)
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return ef
+}
+
+// loadRenameFilters loads container rename filters from environment
+func loadRenameFilters() *filters.RenameFilter {
+	rf := &filters.RenameFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + "RENAME__DENY_ALL"); val != "" {
+		rf.DenyAll = parseBool(val)
+		hasFilter = true
+	}
+
+	if allowedNames := getEnvArray("RENAME__ALLOWED_NAMES"); len(allowedNames) > 0 {
+		rf.AllowedNames = allowedNames
+		hasFilter = true
+	}
+
+	if deniedNames := getEnvArray("RENAME__DENIED_NAMES"); len(deniedNames) > 0 {
+		rf.DeniedNames = deniedNames
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return rf
+}
+
+// loadCommitFilters loads container commit filters from environment
+func loadCommitFilters() *filters.CommitFilter {
+	cf := &filters.CommitFilter{}
+	hasFilter := false
+
+	if val := os.Getenv(envPrefix + "COMMIT__DENY_ALL"); val != "" {
+		cf.DenyAll = parseBool(val)
+		hasFilter = true
+	}
+
+	if deniedChanges := getEnvArray("COMMIT__DENIED_CHANGES"); len(deniedChanges) > 0 {
+		cf.DeniedChanges = deniedChanges
+		hasFilter = true
+	}
+
 	if !hasFilter {
 		return nil
 	}
 	return cf
 }
 
+// loadOwnershipFilters loads the caller's ownership labels from environment
+func loadOwnershipFilters() *filters.OwnershipFilter {
+	labels := getEnvMap("OWNERSHIP__LABELS")
+	if len(labels) == 0 {
+		return nil
+	}
+	return &filters.OwnershipFilter{Labels: labels}
+}
+
+// loadVisibilityFilters loads the selector of the objects visible to the caller from environment
+func loadVisibilityFilters() *filters.VisibilityFilter {
+	vf := &filters.VisibilityFilter{}
+	hasFilter := false
+
+	if labels := getEnvMap("VISIBILITY__LABELS"); len(labels) > 0 {
+		vf.Labels = labels
+		hasFilter = true
+	}
+
+	if names := getEnvArray("VISIBILITY__NAMES"); len(names) > 0 {
+		vf.Names = names
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return vf
+}
+
+// loadMutationFilters loads the changes made to creation requests from environment
+func loadMutationFilters() *filters.MutationFilter {
+	mf := &filters.MutationFilter{}
+	hasFilter := false
+
+	if cm := loadContainerMutations(); cm != nil {
+		mf.Containers = cm
+		hasFilter = true
+	}
+
+	if labels := getEnvMap("MUTATIONS__VOLUMES__LABELS"); len(labels) > 0 {
+		mf.Volumes = &filters.LabelMutation{Labels: labels}
+		hasFilter = true
+	}
+
+	if labels := getEnvMap("MUTATIONS__NETWORKS__LABELS"); len(labels) > 0 {
+		mf.Networks = &filters.LabelMutation{Labels: labels}
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return mf
+}
+
+// loadContainerMutations loads the settings forced on container creations from environment
+func loadContainerMutations() *filters.ContainerMutation {
+	cm := &filters.ContainerMutation{}
+	hasFilter := false
+
+	if labels := getEnvMap("MUTATIONS__CONTAINERS__LABELS"); len(labels) > 0 {
+		cm.Labels = labels
+		hasFilter = true
+	}
+
+	if securityOpt := getEnvArray("MUTATIONS__CONTAINERS__SECURITY_OPT"); len(securityOpt) > 0 {
+		cm.SecurityOpt = securityOpt
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "MUTATIONS__CONTAINERS__DEFAULT_MEMORY"); val != "" {
+		cm.DefaultMemory = strings.TrimSpace(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "MUTATIONS__CONTAINERS__DEFAULT_PIDS_LIMIT"); val != "" {
+		if pids, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64); err == nil && pids > 0 {
+			cm.DefaultPidsLimit = pids
+			hasFilter = true
+		}
+	}
+
+	if val := os.Getenv(envPrefix + "MUTATIONS__CONTAINERS__READONLY_ROOTFS"); val != "" {
+		cm.ReadonlyRootfs = parseBool(val)
+		hasFilter = true
+	}
+
+	if val := os.Getenv(envPrefix + "MUTATIONS__CONTAINERS__INIT"); val != "" {
+		cm.Init = parseBool(val)
+		hasFilter = true
+	}
+
+	if !hasFilter {
+		return nil
+	}
+	return cm
+}
+
 // loadNetworkFilters charge les filtres de réseaux depuis l'environnement
 func loadNetworkFilters() *filters.NetworkFilter {
 	nf := &filters.NetworkFilter{}
@@ -148,6 +684,11 @@ func loadNetworkFilters() *filters.NetworkFilter {
 		hasFilter = true
 	}
 
+	if lf := loadLabelFilters("NETWORKS"); lf != nil {
+		nf.Labels = lf
+		hasFilter = true
+	}
+
 	if !hasFilter {
 		return nil
 	}
@@ -298,5 +839,54 @@ func MergeFilters(jsonFilter, envFilter *filters.AdvancedFilter) *filters.Advanc
 		result.Images = jsonFilter.Images
 	}
 
+	// Opérations sur les conteneurs existants: env prioritaire
+	if envFilter.Updates != nil {
+		result.Updates = envFilter.Updates
+	} else {
+		result.Updates = jsonFilter.Updates
+	}
+
+	if envFilter.RestartPolicy != nil {
+		result.RestartPolicy = envFilter.RestartPolicy
+	} else {
+		result.RestartPolicy = jsonFilter.RestartPolicy
+	}
+
+	if envFilter.Exec != nil {
+		result.Exec = envFilter.Exec
+	} else {
+		result.Exec = jsonFilter.Exec
+	}
+
+	if envFilter.Rename != nil {
+		result.Rename = envFilter.Rename
+	} else {
+		result.Rename = jsonFilter.Rename
+	}
+
+	if envFilter.Commit != nil {
+		result.Commit = envFilter.Commit
+	} else {
+		result.Commit = jsonFilter.Commit
+	}
+
+	if envFilter.Ownership != nil {
+		result.Ownership = envFilter.Ownership
+	} else {
+		result.Ownership = jsonFilter.Ownership
+	}
+
+	if envFilter.Visibility != nil {
+		result.Visibility = envFilter.Visibility
+	} else {
+		result.Visibility = jsonFilter.Visibility
+	}
+
+	if envFilter.Mutations != nil {
+		result.Mutations = envFilter.Mutations
+	} else {
+		result.Mutations = jsonFilter.Mutations
+	}
+
 	return result
 }
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// fileConfig holds the proxy settings stored in the FILTERS_CONFIG JSON file,
+// next to the advanced filter sections
+type fileConfig struct {
+	Timeouts  *timeoutsFile   `json:"timeouts,omitempty"`
+	Upstreams []*upstreamFile `json:"upstreams,omitempty"`
+	Profiles  []*profileFile  `json:"profiles,omitempty"`
+	Listeners []*listenerFile `json:"listeners,omitempty"`
+}
+
+// loadFileConfig loads the proxy settings from the JSON configuration file.
+// A file that cannot be read or parsed is an error: the proxy must not start
+// without the rules it was given.
+func loadFileConfig(path string) (*fileConfig, error) {
+	fc := &fileConfig{}
+	if path == "" {
+		return fc, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, fc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return fc, nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"dockershield/pkg/filters"
+)
+
+// Listener is a socket or TCP address served by the proxy. Requests accepted
+// by a listener with its own access rules or advanced filters use them instead
+// of the global configuration; matching upstreams and profiles can only narrow them.
+type Listener struct {
+	Name    string // Used by the upstream "listeners" selector and in the logs
+	Socket  string // Unix socket path (unix:///path or /path)
+	Address string // TCP address, when Socket is empty
+	TLS     *ListenTLS
+
+	// Unix socket file settings
+	Perms string // Octal permissions, e.g. "0660" (default SOCKET_PERMS, then 0666)
+	Owner string // User name or uid
+	Group string // Group name or gid
+
+	AccessRules     *AccessRules            // nil: global access rules
+	AdvancedFilters *filters.AdvancedFilter // nil: global advanced filters
+}
+
+// listenerFile is an entry of the "listeners" section of the JSON configuration file
+type listenerFile struct {
+	Name        string                  `json:"name,omitempty"`
+	Socket      string                  `json:"socket,omitempty"`
+	Address     string                  `json:"address,omitempty"`
+	TLS         *ListenTLS              `json:"tls,omitempty"`
+	Perms       string                  `json:"perms,omitempty"`
+	Owner       string                  `json:"owner,omitempty"`
+	Group       string                  `json:"group,omitempty"`
+	AccessRules map[string]bool         `json:"access_rules,omitempty"`
+	Filters     *filters.AdvancedFilter `json:"filters,omitempty"`
+}
+
+// loadListeners builds the listener list from the JSON configuration file.
+// Without a "listeners" section, the proxy serves LISTEN_SOCKET or, if unset,
+// LISTEN_ADDR with the global rules. Entries without socket or address are ignored.
+func loadListeners(entries []*listenerFile, config *Config) []*Listener {
+	socketPerms := getEnv("SOCKET_PERMS", "")
+
+	var listeners []*Listener
+	for _, entry := range entries {
+		if entry == nil || (entry.Socket == "" && entry.Address == "") {
+			continue
+		}
+
+		listener := &Listener{
+			Name:    entry.Name,
+			Socket:  entry.Socket,
+			Address: entry.Address,
+			TLS:     entry.TLS,
+			Perms:   entry.Perms,
+			Owner:   entry.Owner,
+			Group:   entry.Group,
+		}
+		if listener.Name == "" {
+			listener.Name = listener.Socket + listener.Address
+		}
+		if listener.Perms == "" {
+			listener.Perms = socketPerms
+		}
+		if entry.AccessRules != nil {
+			listener.AccessRules = accessRulesFromGrants(entry.AccessRules)
+		}
+		if entry.Filters != nil {
+			listener.AdvancedFilters = entry.Filters
+			if !CanOverrideDefaults() {
+				listener.AdvancedFilters = ApplyDefaults(listener.AdvancedFilters)
+			}
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	if len(listeners) > 0 {
+		return listeners
+	}
+
+	if config.ListenSocket != "" {
+		return []*Listener{{Name: config.ListenSocket, Socket: config.ListenSocket, Perms: socketPerms}}
+	}
+	return []*Listener{{Name: config.ListenAddr, Address: config.ListenAddr, TLS: config.ListenTLS}}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"dockershield/pkg/filters"
+)
+
+// Profile is a named policy for a group of callers, with its own access rules
+// and advanced filters. A request gets the first profile whose selectors all
+// match; other requests keep the rules of their upstream or the global rules.
+type Profile struct {
+	Name            string
+	AccessRules     *AccessRules
+	AdvancedFilters *filters.AdvancedFilter // nil: global advanced filters
+
+	// Selectors
+	CertSubjects []string // Client certificate common name or full subject (mutual TLS)
+	Tokens       []string // API tokens ("Authorization: Bearer"), plain or "sha256:<hex>"
+	ClientCIDRs  []string // Client source networks, e.g. "10.0.0.0/8"
+	UIDs         []uint32 // Unix socket caller user IDs (SO_PEERCRED)
+	GIDs         []uint32 // Unix socket caller primary group IDs (SO_PEERCRED)
+}
+
+// profileFile is an entry of the "profiles" section of the JSON configuration file
+type profileFile struct {
+	Name         string                  `json:"name"`
+	CertSubjects []string                `json:"cert_subjects,omitempty"`
+	Tokens       []string                `json:"tokens,omitempty"`
+	ClientCIDRs  []string                `json:"client_cidrs,omitempty"`
+	UIDs         []uint32                `json:"uids,omitempty"`
+	GIDs         []uint32                `json:"gids,omitempty"`
+	AccessRules  map[string]bool         `json:"access_rules,omitempty"`
+	Filters      *filters.AdvancedFilter `json:"filters,omitempty"`
+}
+
+// loadProfiles builds the profile list from the JSON configuration file.
+// Entries without name or selector are ignored.
+func loadProfiles(entries []*profileFile) []*Profile {
+	var profiles []*Profile
+
+	for _, entry := range entries {
+		if entry == nil || entry.Name == "" {
+			continue
+		}
+		if len(entry.CertSubjects) == 0 && len(entry.Tokens) == 0 && len(entry.ClientCIDRs) == 0 &&
+			len(entry.UIDs) == 0 && len(entry.GIDs) == 0 {
+			continue
+		}
+
+		profile := &Profile{
+			Name:         entry.Name,
+			AccessRules:  accessRulesFromGrants(entry.AccessRules),
+			CertSubjects: entry.CertSubjects,
+			Tokens:       entry.Tokens,
+			ClientCIDRs:  entry.ClientCIDRs,
+			UIDs:         entry.UIDs,
+			GIDs:         entry.GIDs,
+		}
+		if entry.Filters != nil {
+			profile.AdvancedFilters = entry.Filters
+			if !CanOverrideDefaults() {
+				profile.AdvancedFilters = ApplyDefaults(profile.AdvancedFilters)
+			}
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	return profiles
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default timeout profiles
+const (
+	defaultShortTimeout   = 30 * time.Second
+	defaultRequestTimeout = 60 * time.Second
+)
+
+// Timeouts defines the timeout profiles applied according to the request path
+type Timeouts struct {
+	Short      time.Duration // Inspect, list and other read-only calls
+	Default    time.Duration // Other non-streaming calls (create, start, stop...)
+	StreamIdle time.Duration // Streaming calls have no deadline, only a maximum idle time (0 = disabled)
+}
+
+// timeoutsFile is the "timeouts" section of the JSON configuration file
+type timeoutsFile struct {
+	Short      string `json:"short,omitempty"`
+	Default    string `json:"default,omitempty"`
+	StreamIdle string `json:"stream_idle,omitempty"`
+}
+
+// loadTimeouts builds the timeout profiles from defaults, JSON file and environment.
+// Environment variables take priority over the JSON file.
+func loadTimeouts(file *timeoutsFile) *Timeouts {
+	timeouts := &Timeouts{
+		Short:   defaultShortTimeout,
+		Default: defaultRequestTimeout,
+	}
+
+	if file != nil {
+		timeouts.Short = parseDuration(file.Short, timeouts.Short)
+		timeouts.Default = parseDuration(file.Default, timeouts.Default)
+		timeouts.StreamIdle = parseDuration(file.StreamIdle, timeouts.StreamIdle)
+	}
+
+	timeouts.Short = getDurationEnv("TIMEOUT_SHORT", timeouts.Short)
+	timeouts.Default = getDurationEnv("TIMEOUT_DEFAULT", timeouts.Default)
+	timeouts.StreamIdle = getDurationEnv("TIMEOUT_STREAM_IDLE", timeouts.StreamIdle)
+
+	return timeouts
+}
+
+// getDurationEnv gets a duration environment variable or returns a default value
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	return parseDuration(getEnv(key, ""), defaultValue)
+}
+
+// parseDuration parses "90s", "5m" or a plain number of seconds.
+// Empty or invalid values return the default, "0" disables the timeout.
+func parseDuration(value string, defaultValue time.Duration) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultValue
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return defaultValue
+	}
+	return duration
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DockerTLS configures TLS towards a tcp:// Docker daemon started with --tlsverify
+type DockerTLS struct {
+	CAFile             string `json:"ca,omitempty"`                   // CA bundle verifying the daemon certificate
+	CertFile           string `json:"cert,omitempty"`                 // Client certificate presented to the daemon
+	KeyFile            string `json:"key,omitempty"`                  // Client certificate key
+	ServerName         string `json:"server_name,omitempty"`          // Expected name in the daemon certificate
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"` // Do not verify the daemon certificate
+}
+
+// ListenTLS configures TLS on the TCP listener, optionally requiring client certificates
+type ListenTLS struct {
+	CertFile     string `json:"cert"`                  // Server certificate presented to the clients
+	KeyFile      string `json:"key"`                   // Server certificate key
+	ClientCAFile string `json:"client_ca,omitempty"`   // CA bundle verifying client certificates
+	ClientAuth   string `json:"client_auth,omitempty"` // "require" (default with a client CA) or "optional"
+}
+
+// Client certificate modes of ListenTLS.ClientAuth
+const (
+	ClientAuthRequire  = "require"
+	ClientAuthOptional = "optional"
+)
+
+// loadListenTLS loads the TLS settings of the TCP listener from the environment.
+// TLS is enabled by LISTEN_TLS_CERT, client certificates by LISTEN_TLS_CLIENT_CA.
+func loadListenTLS() *ListenTLS {
+	certFile := getEnv("LISTEN_TLS_CERT", "")
+	if certFile == "" {
+		return nil
+	}
+
+	return &ListenTLS{
+		CertFile:     certFile,
+		KeyFile:      getEnv("LISTEN_TLS_KEY", ""),
+		ClientCAFile: getEnv("LISTEN_TLS_CLIENT_CA", ""),
+		ClientAuth:   strings.ToLower(getEnv("LISTEN_TLS_CLIENT_AUTH", "")),
+	}
+}
+
+// ServerConfig builds the TLS configuration of the listener.
+// Hijacked endpoints (attach, exec) need HTTP/1.1, so HTTP/2 is not offered.
+func (t *ListenTLS) ServerConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+	}
+
+	if t.ClientCAFile == "" {
+		if t.ClientAuth != "" {
+			return nil, fmt.Errorf("client_auth %q requires a client CA", t.ClientAuth)
+		}
+		return tlsConfig, nil
+	}
+
+	pool, err := loadCertPool(t.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ClientCAs = pool
+
+	switch t.ClientAuth {
+	case "", ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("unknown client_auth %q (expected %q or %q)", t.ClientAuth, ClientAuthRequire, ClientAuthOptional)
+	}
+
+	return tlsConfig, nil
+}
+
+// loadDockerTLS loads the TLS settings of DOCKER_SOCKET from the environment.
+// DOCKER_CERT_PATH and DOCKER_TLS_VERIFY follow the Docker client rules: the
+// certificates are read from ca.pem, cert.pem and key.pem, and the daemon
+// certificate is only verified when DOCKER_TLS_VERIFY is set.
+func loadDockerTLS() *DockerTLS {
+	certPath := getEnv("DOCKER_CERT_PATH", "")
+	verify := getEnv("DOCKER_TLS_VERIFY", "")
+
+	dockerTLS := &DockerTLS{
+		CAFile:     getEnv("DOCKER_TLS_CA", certPathFile(certPath, "ca.pem")),
+		CertFile:   getEnv("DOCKER_TLS_CERT", certPathFile(certPath, "cert.pem")),
+		KeyFile:    getEnv("DOCKER_TLS_KEY", certPathFile(certPath, "key.pem")),
+		ServerName: getEnv("DOCKER_TLS_SERVER_NAME", ""),
+	}
+
+	if verify == "" && dockerTLS.CAFile == "" && dockerTLS.CertFile == "" && dockerTLS.ServerName == "" {
+		return nil
+	}
+
+	dockerTLS.InsecureSkipVerify = certPath != "" && verify == ""
+	return dockerTLS
+}
+
+// certPathFile returns the path of a file in DOCKER_CERT_PATH, or "" if unset
+func certPathFile(certPath, name string) string {
+	if certPath == "" {
+		return ""
+	}
+	return filepath.Join(certPath, name)
+}
+
+// ClientConfig builds the TLS client configuration used to reach the daemon
+func (t *DockerTLS) ClientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify, // #nosec G402 -- explicit opt-out, as with DOCKER_TLS_VERIFY
+	}
+
+	if t.CAFile != "" {
+		pool, err := loadCertPool(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// loadCertPool reads a PEM CA bundle
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no valid certificate found in CA file %s", caFile)
+	}
+	return pool, nil
+}
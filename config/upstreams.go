@@ -0,0 +1,79 @@
+package config
+
+import (
+	"strings"
+
+	"dockershield/pkg/filters"
+)
+
+// Upstream is an additional Docker daemon fronted by the proxy, with its own
+// access rules and advanced filters. A request is routed to the first upstream
+// whose selectors all match; other requests go to DOCKER_SOCKET.
+type Upstream struct {
+	Name            string
+	DockerSocket    string
+	DockerTLS       *DockerTLS // TLS towards a tcp:// daemon (optional)
+	AccessRules     *AccessRules
+	AdvancedFilters *filters.AdvancedFilter // nil: global advanced filters
+
+	// Selectors
+	Listeners   []string // Listener names (LISTEN_SOCKET or LISTEN_ADDR value)
+	ClientCIDRs []string // Client source networks, e.g. "10.0.0.0/8"
+	PathPrefix  string   // Path prefix, e.g. "/rootless", stripped before forwarding
+}
+
+// upstreamFile is an entry of the "upstreams" section of the JSON configuration file
+type upstreamFile struct {
+	Name         string                  `json:"name"`
+	DockerSocket string                  `json:"docker_socket"`
+	TLS          *DockerTLS              `json:"tls,omitempty"`
+	Listeners    []string                `json:"listeners,omitempty"`
+	ClientCIDRs  []string                `json:"client_cidrs,omitempty"`
+	PathPrefix   string                  `json:"path_prefix,omitempty"`
+	AccessRules  map[string]bool         `json:"access_rules,omitempty"`
+	Filters      *filters.AdvancedFilter `json:"filters,omitempty"`
+}
+
+// loadUpstreams builds the upstream list from the JSON configuration file.
+// Entries without name, socket or selector are ignored.
+func loadUpstreams(entries []*upstreamFile) []*Upstream {
+	var upstreams []*Upstream
+
+	for _, entry := range entries {
+		if entry == nil || entry.Name == "" || entry.DockerSocket == "" {
+			continue
+		}
+		if len(entry.Listeners) == 0 && len(entry.ClientCIDRs) == 0 && entry.PathPrefix == "" {
+			continue
+		}
+
+		upstream := &Upstream{
+			Name:         entry.Name,
+			DockerSocket: entry.DockerSocket,
+			DockerTLS:    entry.TLS,
+			AccessRules:  accessRulesFromGrants(entry.AccessRules),
+			Listeners:    entry.Listeners,
+			ClientCIDRs:  entry.ClientCIDRs,
+			PathPrefix:   normalizePathPrefix(entry.PathPrefix),
+		}
+		if entry.Filters != nil {
+			upstream.AdvancedFilters = entry.Filters
+			if !CanOverrideDefaults() {
+				upstream.AdvancedFilters = ApplyDefaults(upstream.AdvancedFilters)
+			}
+		}
+
+		upstreams = append(upstreams, upstream)
+	}
+
+	return upstreams
+}
+
+// normalizePathPrefix returns the prefix with a leading slash and no trailing slash
+func normalizePathPrefix(prefix string) string {
+	prefix = strings.TrimRight(strings.TrimSpace(prefix), "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
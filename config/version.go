@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"net/http"
 	"strings"
 	"time"
 
@@ -10,13 +11,25 @@ import (
 )
 
 // DetectDockerAPIVersion détecte automatiquement la version de l'API Docker
-func DetectDockerAPIVersion(dockerSocket string, logger *logrus.Logger) string {
+func DetectDockerAPIVersion(dockerSocket string, dockerTLS *DockerTLS, logger *logrus.Logger) string {
 	// Créer un client Docker
 	opts := []client.Opt{
 		client.FromEnv,
 		client.WithAPIVersionNegotiation(),
 	}
 
+	// Utiliser la même configuration TLS que le proxy
+	if dockerTLS != nil && strings.HasPrefix(dockerSocket, "tcp://") {
+		tlsConfig, err := dockerTLS.ClientConfig()
+		if err != nil {
+			logger.Warnf("Failed to load Docker TLS configuration for version detection: %v", err)
+			return "v1.41" // Fallback version
+		}
+		opts = append(opts, client.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		}))
+	}
+
 	// Si un socket spécifique est fourni, l'utiliser
 	if dockerSocket != "" && strings.HasPrefix(dockerSocket, "unix://") {
 		socketPath := strings.TrimPrefix(dockerSocket, "unix://")
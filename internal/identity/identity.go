@@ -0,0 +1,45 @@
+// Package identity carries what the proxy knows about the caller of a request
+package identity
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	listenerKey contextKey = iota
+	peerKey
+)
+
+// WithListener returns a context tagged with the name of the listener that accepted the connection
+func WithListener(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, listenerKey, name)
+}
+
+// Listener returns the name of the listener that accepted the request
+func Listener(ctx context.Context) string {
+	name, _ := ctx.Value(listenerKey).(string)
+	return name
+}
+
+// ClientCertificate returns the client certificate of a mutual TLS request,
+// or nil if the client did not present a certificate verified by the listener
+func ClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// BearerToken returns the API token sent as "Authorization: Bearer <token>", or ""
+func BearerToken(r *http.Request) string {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
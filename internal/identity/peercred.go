@@ -0,0 +1,40 @@
+package identity
+
+import (
+	"context"
+	"net"
+)
+
+// PeerCredentials identifies the local process connected to the unix listener.
+// They are read once per connection (SO_PEERCRED) and reflect the caller at connect time.
+type PeerCredentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// WithPeer returns a context carrying the credentials of the connected process
+func WithPeer(ctx context.Context, creds *PeerCredentials) context.Context {
+	return context.WithValue(ctx, peerKey, creds)
+}
+
+// Peer returns the credentials of the process that sent the request,
+// or nil if the connection did not come through the unix listener
+func Peer(ctx context.Context) *PeerCredentials {
+	creds, _ := ctx.Value(peerKey).(*PeerCredentials)
+	return creds
+}
+
+// PeerConnContext tags each unix socket connection with its peer credentials.
+// It is meant for http.Server.ConnContext; other connections are left untouched.
+func PeerConnContext(ctx context.Context, conn net.Conn) context.Context {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+	creds, err := ReadPeerCredentials(unixConn)
+	if err != nil {
+		return ctx
+	}
+	return WithPeer(ctx, creds)
+}
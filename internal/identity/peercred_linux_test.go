@@ -0,0 +1,50 @@
+package identity
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeerConnContext(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "proxy.sock"))
+	if err != nil {
+		t.Fatalf("Failed to listen on unix socket: %v", err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial unix socket: %v", err)
+	}
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept connection: %v", err)
+	}
+	defer conn.Close()
+
+	creds := Peer(PeerConnContext(context.Background(), conn))
+	if creds == nil {
+		t.Fatal("Expected peer credentials on a unix connection")
+	}
+	if creds.PID != int32(os.Getpid()) {
+		t.Errorf("Expected pid %d, got %d", os.Getpid(), creds.PID)
+	}
+	if creds.UID != uint32(os.Getuid()) || creds.GID != uint32(os.Getgid()) {
+		t.Errorf("Expected uid/gid %d/%d, got %d/%d", os.Getuid(), os.Getgid(), creds.UID, creds.GID)
+	}
+}
+
+func TestPeerConnContextIgnoresOtherConnections(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	if creds := Peer(PeerConnContext(context.Background(), server)); creds != nil {
+		t.Errorf("Expected no peer credentials, got %+v", creds)
+	}
+}
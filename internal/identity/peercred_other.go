@@ -0,0 +1,13 @@
+//go:build !linux
+
+package identity
+
+import (
+	"errors"
+	"net"
+)
+
+// ReadPeerCredentials is only implemented on Linux (SO_PEERCRED)
+func ReadPeerCredentials(conn *net.UnixConn) (*PeerCredentials, error) {
+	return nil, errors.New("peer credentials are not supported on this platform")
+}
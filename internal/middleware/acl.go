@@ -12,16 +12,14 @@ import (
 func ACLMiddleware(matcher *rules.Matcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if advanced filter already authorized this request
-		// This allows DKRPRX__ variables to override ACL settings
-		if authorized, exists := c.Get("advanced_filter_authorized"); exists && authorized.(bool) {
-			c.Next()
-			return
-		}
+		// This allows DKRPRX__ variables to override the global ACL settings,
+		// never the rules of a listener, upstream or profile
+		authorized := c.GetBool("advanced_filter_authorized")
 
 		method := c.Request.Method
 		path := c.Request.URL.Path
 
-		if !matcher.IsAllowed(method, path) {
+		if !policiesAllow(c, matcher, authorized, method, path) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"message": "Access to this API endpoint is not allowed",
 				"path":    path,
@@ -34,3 +32,21 @@ func ACLMiddleware(matcher *rules.Matcher) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// policiesAllow checks the request against the access rules of every policy
+// selected for it. The global rules apply when no policy has its own, unless
+// an advanced filter authorized the request.
+func policiesAllow(c *gin.Context, matcher *rules.Matcher, filterAuthorized bool, method, path string) bool {
+	checked := false
+	for _, policy := range Policies(c) {
+		if policy.Matcher == nil {
+			continue
+		}
+		if !policy.Matcher.IsAllowed(method, path) {
+			return false
+		}
+		checked = true
+	}
+
+	return checked || filterAuthorized || matcher.IsAllowed(method, path)
+}
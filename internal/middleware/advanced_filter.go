@@ -3,10 +3,11 @@ package middleware
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"regexp"
-	"strings"
+	"strconv"
 
 	"dockershield/pkg/filters"
 
@@ -14,53 +15,30 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// AdvancedFilterMiddleware crée un middleware pour les filtres avancés
-func AdvancedFilterMiddleware(filter *filters.AdvancedFilter, logger *logrus.Logger) gin.HandlerFunc {
-	if filter == nil {
-		// Pas de filtres avancés configurés
-		return func(c *gin.Context) {
-			c.Next()
-		}
-	}
+// Inspector interroge le démon Docker sélectionné pour la requête (implémenté par proxy.Handler)
+type Inspector interface {
+	// Inspect envoie un GET sur path et décode la réponse JSON dans v ; retourne le code HTTP du démon
+	Inspect(c *gin.Context, path string, v interface{}) (int, error)
+}
 
+// AdvancedFilterMiddleware crée un middleware pour les filtres avancés.
+// L'inspector sert aux règles qui dépendent de l'état du démon (utilisateur de l'image).
+func AdvancedFilterMiddleware(defaultFilter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		method := c.Request.Method
-		path := c.Request.URL.Path
-
 		// Filtrer uniquement les opérations de création/modification
-		if method != "POST" && method != "PUT" {
+		if c.Request.Method != "POST" && c.Request.Method != "PUT" {
 			c.Next()
 			return
 		}
 
-		// Déterminer le type d'opération et marquer si le filtre avancé a autorisé
+		// Les filtres de chaque politique sélectionnée (écoute, upstream, profil) s'appliquent tous
 		handled := false
-		if matched, _ := regexp.MatchString(`/containers/create`, path); matched {
-			handled = true
-			if !checkContainerCreate(c, filter, logger) {
-				return
-			}
-		} else if matched, _ := regexp.MatchString(`/volumes/create`, path); matched {
-			handled = true
-			if !checkVolumeCreate(c, filter, logger) {
-				return
-			}
-		} else if matched, _ := regexp.MatchString(`/networks/create`, path); matched {
-			handled = true
-			if !checkNetworkCreate(c, filter, logger) {
-				return
-			}
-		} else if matched, _ := regexp.MatchString(`/images/create`, path); matched {
-			handled = true
-			if !checkImageCreate(c, filter, logger) {
-				return
-			}
-		} else if matched, _ := regexp.MatchString(`/build`, path); matched {
-			handled = true
-			// Vérifier le tag de l'image dans les query params
-			if !checkImageBuild(c, filter, logger) {
+		for _, filter := range filtersFor(c, defaultFilter) {
+			filterHandled, ok := applyAdvancedFilter(c, filter, inspector, logger)
+			if !ok {
 				return
 			}
+			handled = handled || filterHandled
 		}
 
 		// Si le filtre avancé a traité et autorisé la requête, marquer dans le contexte
@@ -73,18 +51,117 @@ func AdvancedFilterMiddleware(filter *filters.AdvancedFilter, logger *logrus.Log
 	}
 }
 
-// checkContainerCreate vérifie la création de conteneur
-func checkContainerCreate(c *gin.Context, filter *filters.AdvancedFilter, logger *logrus.Logger) bool {
+// applyAdvancedFilter vérifie la requête avec un filtre ; handled indique que
+// le filtre a traité (et autorisé) une création, ok=false que la requête a été refusée
+func applyAdvancedFilter(c *gin.Context, filter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) (handled bool, ok bool) {
+	path := c.Request.URL.Path
+
+	// Déterminer le type d'opération
+	if matched, _ := regexp.MatchString(`/containers/create`, path); matched {
+		return true, checkContainerCreate(c, filter, inspector, logger)
+	} else if matched, _ := regexp.MatchString(`/containers/[^/]+/update$`, path); matched {
+		// Les opérations sur un conteneur existant sont restreintes par les filtres,
+		// mais restent soumises à l'ACL (CONTAINERS, EXEC, COMMIT)
+		return false, checkContainerUpdate(c, filter, logger)
+	} else if matched, _ := regexp.MatchString(`/containers/[^/]+/exec$`, path); matched {
+		return false, checkExecCreate(c, filter, inspector, logger)
+	} else if matched, _ := regexp.MatchString(`/containers/[^/]+/rename$`, path); matched {
+		return false, checkContainerRename(c, filter, logger)
+	} else if matched, _ := regexp.MatchString(`/commit$`, path); matched {
+		return false, checkCommit(c, filter, logger)
+	} else if matched, _ := regexp.MatchString(`/volumes/create`, path); matched {
+		return true, checkVolumeCreate(c, filter, logger)
+	} else if matched, _ := regexp.MatchString(`/networks/create`, path); matched {
+		return true, checkNetworkCreate(c, filter, logger)
+	} else if matched, _ := regexp.MatchString(`/images/create`, path); matched {
+		return true, checkImageCreate(c, filter, logger)
+	} else if matched, _ := regexp.MatchString(`/build`, path); matched {
+		// Vérifier le tag de l'image dans les query params
+		return true, checkImageBuild(c, filter, logger)
+	}
+
+	return false, true
+}
+
+// readJSONBody lit le corps JSON de la requête et le remet en place pour le proxy
+func readJSONBody(c *gin.Context) (map[string]interface{}, bool) {
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
-		return false
+		c.Abort()
+		return nil, false
 	}
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
+	// Les nombres restent des json.Number : un entier au-delà de 2^53
+	// (HostConfig.Memory...) doit atteindre le démon sans perte de précision
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
 	var config map[string]interface{}
-	if err := json.Unmarshal(body, &config); err != nil {
+	err = decoder.Decode(&config)
+	if err == nil {
+		// Comme json.Unmarshal, refuser ce qui suit le document
+		if _, trailing := decoder.Token(); trailing != io.EOF {
+			err = errors.New("trailing data after JSON document")
+		}
+	}
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+		c.Abort()
+		return nil, false
+	}
+	return config, true
+}
+
+// applyMutations remplace le corps transmis au démon par sa version modifiée
+// et journalise chaque modification appliquée
+func applyMutations(c *gin.Context, config map[string]interface{}, patches []string, operation string, logger *logrus.Logger) bool {
+	if len(patches) == 0 {
+		return true
+	}
+
+	body, err := json.Marshal(config)
+	if err != nil {
+		logger.Errorf("%s: failed to encode the mutated body: %v", operation, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode request body"})
+		c.Abort()
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+	for _, patch := range patches {
+		logger.Infof("%s mutated: %s", operation, patch)
+	}
+	return true
+}
+
+// denyRequest répond 403 avec la raison du refus
+func denyRequest(c *gin.Context, message, reason string) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"message": message,
+		"reason":  reason,
+	})
+	c.Abort()
+}
+
+// checkContainerCreate vérifie la création de conteneur
+func checkContainerCreate(c *gin.Context, filter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) bool {
+	config, ok := readJSONBody(c)
+	if !ok {
+		return false
+	}
+
+	// Les modifications déclarées s'appliquent avant les vérifications
+	patches, err := filter.MutateContainerCreate(config)
+	if err != nil {
+		logger.Warnf("Container creation denied: %v", err)
+		denyRequest(c, "Container creation denied by advanced filter", err.Error())
+		return false
+	}
+	if !applyMutations(c, config, patches, "Container creation", logger) {
 		return false
 	}
 
@@ -92,6 +169,16 @@ func checkContainerCreate(c *gin.Context, filter *filters.AdvancedFilter, logger
 	image, _ := config["Image"].(string)
 	name := c.Query("name")
 
+	// Sans --user, le conteneur tourne avec l'utilisateur de l'image : le vérifier à sa place.
+	// Le corps transmis au démon reste inchangé.
+	if image != "" && inspector != nil && filter.ImageUserNeeded(config) {
+		user, ok := inspectImageUser(c, inspector, image, logger)
+		if !ok {
+			return false
+		}
+		config["User"] = user
+	}
+
 	allowed, reason := filter.CheckContainerCreate(image, name, config)
 	if !allowed {
 		logger.Warnf("Container creation denied: %s", reason)
@@ -106,18 +193,143 @@ func checkContainerCreate(c *gin.Context, filter *filters.AdvancedFilter, logger
 	return true
 }
 
+// inspectImageUser retourne l'utilisateur configuré dans l'image (USER du Dockerfile).
+// Une image absente est signalée comme le ferait le démon, pour que le client la tire puis réessaie.
+func inspectImageUser(c *gin.Context, inspector Inspector, image string, logger *logrus.Logger) (string, bool) {
+	var inspect struct {
+		Config struct {
+			User string `json:"User"`
+		} `json:"Config"`
+	}
+
+	status, err := inspector.Inspect(c, "/images/"+image+"/json", &inspect)
+	switch {
+	case err != nil:
+		logger.Errorf("Failed to inspect image %s: %v", image, err)
+		c.JSON(http.StatusBadGateway, gin.H{"message": "failed to inspect image: " + image})
+	case status == http.StatusNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "No such image: " + image})
+	case status != http.StatusOK:
+		logger.Errorf("Failed to inspect image %s: docker answered %d", image, status)
+		c.JSON(http.StatusBadGateway, gin.H{"message": "failed to inspect image: " + image})
+	default:
+		return inspect.Config.User, true
+	}
+
+	c.Abort()
+	return "", false
+}
+
+// checkContainerUpdate vérifie la modification d'un conteneur (docker update)
+func checkContainerUpdate(c *gin.Context, filter *filters.AdvancedFilter, logger *logrus.Logger) bool {
+	update, ok := readJSONBody(c)
+	if !ok {
+		return false
+	}
+
+	if allowed, reason := filter.CheckContainerUpdate(update); !allowed {
+		logger.Warnf("Container update denied: %s", reason)
+		denyRequest(c, "Container update denied by advanced filter", reason)
+		return false
+	}
+
+	return true
+}
+
+// execPathPattern extrait l'identifiant du conteneur cible d'un exec
+var execPathPattern = regexp.MustCompile(`/containers/([^/]+)/exec$`)
+
+// checkExecCreate vérifie la création d'une commande exec
+func checkExecCreate(c *gin.Context, filter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) bool {
+	config, ok := readJSONBody(c)
+	if !ok {
+		return false
+	}
+
+	// Les règles exec dépendent du nom, de l'image et des labels du conteneur cible
+	var target *filters.ExecTarget
+	if match := execPathPattern.FindStringSubmatch(c.Request.URL.Path); match != nil && inspector != nil && filter.ExecTargetNeeded() {
+		if target, ok = inspectExecTarget(c, inspector, match[1], logger); !ok {
+			return false
+		}
+	}
+
+	if allowed, reason := filter.CheckExecCreate(target, config); !allowed {
+		logger.Warnf("Exec creation denied: %s", reason)
+		denyRequest(c, "Exec creation denied by advanced filter", reason)
+		return false
+	}
+
+	return true
+}
+
+// inspectExecTarget retourne le nom, l'image et les labels du conteneur cible
+func inspectExecTarget(c *gin.Context, inspector Inspector, id string, logger *logrus.Logger) (*filters.ExecTarget, bool) {
+	var inspect struct {
+		Name   string `json:"Name"`
+		Config struct {
+			Image  string            `json:"Image"`
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
+	}
+
+	status, err := inspector.Inspect(c, "/containers/"+id+"/json", &inspect)
+	switch {
+	case err != nil:
+		logger.Errorf("Failed to inspect container %s: %v", id, err)
+		c.JSON(http.StatusBadGateway, gin.H{"message": "failed to inspect container: " + id})
+	case status == http.StatusNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"message": "No such container: " + id})
+	case status != http.StatusOK:
+		logger.Errorf("Failed to inspect container %s: docker answered %d", id, status)
+		c.JSON(http.StatusBadGateway, gin.H{"message": "failed to inspect container: " + id})
+	default:
+		return &filters.ExecTarget{
+			Name:   inspect.Name,
+			Image:  inspect.Config.Image,
+			Labels: inspect.Config.Labels,
+		}, true
+	}
+
+	c.Abort()
+	return nil, false
+}
+
+// checkContainerRename vérifie le renommage d'un conteneur
+func checkContainerRename(c *gin.Context, filter *filters.AdvancedFilter, logger *logrus.Logger) bool {
+	if allowed, reason := filter.CheckContainerRename(c.Query("name")); !allowed {
+		logger.Warnf("Container rename denied: %s", reason)
+		denyRequest(c, "Container rename denied by advanced filter", reason)
+		return false
+	}
+
+	return true
+}
+
+// checkCommit vérifie la création d'une image depuis un conteneur
+func checkCommit(c *gin.Context, filter *filters.AdvancedFilter, logger *logrus.Logger) bool {
+	image := c.Query("repo")
+	if image != "" && c.Query("tag") != "" {
+		image += ":" + c.Query("tag")
+	}
+
+	if allowed, reason := filter.CheckCommit(image, c.QueryArray("changes")); !allowed {
+		logger.Warnf("Container commit denied: %s", reason)
+		denyRequest(c, "Container commit denied by advanced filter", reason)
+		return false
+	}
+
+	return true
+}
+
 // checkVolumeCreate vérifie la création de volume
 func checkVolumeCreate(c *gin.Context, filter *filters.AdvancedFilter, logger *logrus.Logger) bool {
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+	config, ok := readJSONBody(c)
+	if !ok {
 		return false
 	}
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
-	var config map[string]interface{}
-	if err := json.Unmarshal(body, &config); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+	if !applyMutations(c, config, filter.MutateVolumeCreate(config), "Volume creation", logger) {
 		return false
 	}
 
@@ -127,20 +339,14 @@ func checkVolumeCreate(c *gin.Context, filter *filters.AdvancedFilter, logger *l
 	// Extraire le chemin du host si présent dans les options
 	hostPath := ""
 	if driverOpts, ok := config["DriverOpts"].(map[string]interface{}); ok {
-		if device, ok := driverOpts["device"].(string); ok {
-			hostPath = device
-		}
-		if path, ok := driverOpts["o"].(string); ok {
-			if strings.Contains(path, "device=") {
-				parts := strings.Split(path, "device=")
-				if len(parts) > 1 {
-					hostPath = strings.Split(parts[1], ",")[0]
-				}
-			}
-		}
+		hostPath = filters.DriverOptsHostPath(driverOpts)
 	}
 
 	allowed, reason := filter.CheckVolumeMount(name, hostPath, driver)
+	if allowed {
+		labels, _ := config["Labels"].(map[string]interface{})
+		allowed, reason = filter.CheckVolumeLabels(labels)
+	}
 	if !allowed {
 		logger.Warnf("Volume creation denied: %s", reason)
 		c.JSON(http.StatusForbidden, gin.H{
@@ -156,16 +362,12 @@ func checkVolumeCreate(c *gin.Context, filter *filters.AdvancedFilter, logger *l
 
 // checkNetworkCreate vérifie la création de réseau
 func checkNetworkCreate(c *gin.Context, filter *filters.AdvancedFilter, logger *logrus.Logger) bool {
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+	config, ok := readJSONBody(c)
+	if !ok {
 		return false
 	}
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
-	var config map[string]interface{}
-	if err := json.Unmarshal(body, &config); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON"})
+	if !applyMutations(c, config, filter.MutateNetworkCreate(config), "Network creation", logger) {
 		return false
 	}
 
@@ -173,6 +375,10 @@ func checkNetworkCreate(c *gin.Context, filter *filters.AdvancedFilter, logger *
 	driver, _ := config["Driver"].(string)
 
 	allowed, reason := filter.CheckNetworkCreate(name, driver)
+	if allowed {
+		labels, _ := config["Labels"].(map[string]interface{})
+		allowed, reason = filter.CheckNetworkLabels(labels)
+	}
 	if !allowed {
 		logger.Warnf("Network creation denied: %s", reason)
 		c.JSON(http.StatusForbidden, gin.H{
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dockershield/pkg/filters"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAdvancedFilterMiddlewareContainerOperations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	filter := &filters.AdvancedFilter{
+		Updates: &filters.UpdateFilter{Resources: &filters.ResourceFilter{MaxMemory: "1g"}},
+		Exec:    &filters.ExecFilter{DenyPrivileged: true},
+		Rename:  &filters.RenameFilter{DeniedNames: []string{"^dockershield$"}},
+		Commit:  &filters.CommitFilter{DenyAll: true},
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Update within limits", "/v1.43/containers/web/update", `{"Memory":536870912}`, http.StatusOK},
+		{"Update above limits", "/v1.43/containers/web/update", `{"Memory":4294967296}`, http.StatusForbidden},
+		{"Exec", "/v1.43/containers/web/exec", `{"Cmd":["ls"]}`, http.StatusOK},
+		{"Privileged exec", "/v1.43/containers/web/exec", `{"Cmd":["sh"],"Privileged":true}`, http.StatusForbidden},
+		{"Exec with invalid JSON", "/v1.43/containers/web/exec", `{`, http.StatusBadRequest},
+		{"Rename", "/v1.43/containers/web/rename?name=web-old", ``, http.StatusOK},
+		{"Rename to a denied name", "/v1.43/containers/web/rename?name=dockershield", ``, http.StatusForbidden},
+		{"Commit", "/v1.43/commit?container=web&repo=backup", ``, http.StatusForbidden},
+		{"Container named build", "/v1.43/containers/build/update", `{"Memory":4294967296}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorized := false
+
+			router := gin.New()
+			router.Use(AdvancedFilterMiddleware(filter, nil, logrus.New()))
+			router.POST("/*path", func(c *gin.Context) {
+				authorized = c.GetBool("advanced_filter_authorized")
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			// These operations stay subject to the ACL
+			if authorized {
+				t.Error("Expected the ACL not to be bypassed")
+			}
+		})
+	}
+}
+
+func TestAdvancedFilterMiddlewareMutations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	owner := map[string]string{"com.example.owner": "ci"}
+	filter := &filters.AdvancedFilter{
+		Containers: &filters.ContainerFilter{RequireNoNewPrivileges: true},
+		Ownership:  &filters.OwnershipFilter{Labels: owner},
+		Mutations: &filters.MutationFilter{
+			Containers: &filters.ContainerMutation{Labels: owner, SecurityOpt: []string{"no-new-privileges"}, Init: true},
+			Networks:   &filters.LabelMutation{Labels: owner},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		expectedBody string
+	}{
+		{"Container creation", "/v1.43/containers/create", `{"Image":"nginx"}`,
+			`{"HostConfig":{"Init":true,"SecurityOpt":["no-new-privileges"]},"Image":"nginx","Labels":{"com.example.owner":"ci"}}`},
+		{"Network creation", "/v1.43/networks/create", `{"Name":"app","Labels":{"com.example.owner":"prod"}}`,
+			`{"Labels":{"com.example.owner":"ci"},"Name":"app"}`},
+		{"Unchanged volume creation", "/v1.43/volumes/create", `{"Name":"data", "Labels":{"com.example.owner":"ci"}}`,
+			`{"Name":"data", "Labels":{"com.example.owner":"ci"}}`},
+		// 2^53 + 1 is rounded by a float64 decoding
+		{"Large integers kept", "/v1.43/containers/create", `{"Image":"nginx","HostConfig":{"Memory":9007199254740993,"NanoCpus":1500000000}}`,
+			`{"HostConfig":{"Init":true,"Memory":9007199254740993,"NanoCpus":1500000000,"SecurityOpt":["no-new-privileges"]},"Image":"nginx","Labels":{"com.example.owner":"ci"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded string
+			var length int64
+
+			router := gin.New()
+			router.Use(AdvancedFilterMiddleware(filter, nil, logrus.New()))
+			router.POST("/*path", func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				forwarded, length = string(body), c.Request.ContentLength
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d (%s)", w.Code, w.Body.String())
+			}
+			if forwarded != tt.expectedBody {
+				t.Errorf("Expected forwarded body %s, got %s", tt.expectedBody, forwarded)
+			}
+			if length != int64(len(forwarded)) {
+				t.Errorf("Expected Content-Length %d, got %d", len(forwarded), length)
+			}
+		})
+	}
+}
+
+func TestAdvancedFilterMiddlewareAppliesEveryPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	listenerFilter := &filters.AdvancedFilter{Containers: &filters.ContainerFilter{DenyPrivileged: true}}
+	profileFilter := &filters.AdvancedFilter{Containers: &filters.ContainerFilter{AllowedImages: []string{"^nginx"}}}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		AddPolicy(c, &Policy{Name: "listener", Filter: listenerFilter})
+		AddPolicy(c, &Policy{Name: "profile", Filter: profileFilter})
+	})
+	router.Use(AdvancedFilterMiddleware(nil, nil, logrus.New()))
+	router.POST("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"Allowed by both", `{"Image":"nginx"}`, http.StatusOK},
+		{"Denied by the listener", `{"Image":"nginx","HostConfig":{"Privileged":true}}`, http.StatusForbidden},
+		{"Denied by the profile", `{"Image":"alpine"}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1.43/containers/create", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
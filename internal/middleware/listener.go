@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"dockershield/config"
+	"dockershield/internal/identity"
+	"dockershield/pkg/filters"
+	"dockershield/pkg/rules"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListenerMiddleware applies the access rules and advanced filters attached to
+// the listener that accepted the request. Upstream and profile middlewares run
+// afterwards and add their own policies, which can only narrow the listener
+// rules. A listener without its own filters keeps defaultFilter.
+func ListenerMiddleware(listeners []*config.Listener, defaultFilter *filters.AdvancedFilter) gin.HandlerFunc {
+	policies := make(map[string]*Policy, len(listeners))
+	for _, listener := range listeners {
+		if listener.AccessRules == nil && listener.AdvancedFilters == nil {
+			continue
+		}
+
+		policy := &Policy{Name: listener.Name, Filter: listener.AdvancedFilters}
+		if listener.AccessRules != nil {
+			policy.Matcher = rules.NewMatcher(listener.AccessRules)
+		}
+		if policy.Filter == nil {
+			policy.Filter = defaultFilter
+		}
+		policies[listener.Name] = policy
+	}
+
+	return func(c *gin.Context) {
+		if policy, ok := policies[identity.Listener(c.Request.Context())]; ok {
+			AddPolicy(c, policy)
+		}
+		c.Next()
+	}
+}
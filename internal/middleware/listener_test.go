@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dockershield/config"
+	"dockershield/internal/identity"
+	"dockershield/pkg/filters"
+	"dockershield/pkg/rules"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestListenerMiddlewareAppliesListenerRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	listeners := []*config.Listener{
+		{Name: "admin", Socket: "/run/admin.sock", AccessRules: &config.AccessRules{Info: true, Containers: true}},
+		{Name: "monitoring", Socket: "/run/monitoring.sock", AccessRules: &config.AccessRules{Containers: true}},
+		{Name: "dashboard", Address: ":2376"},
+	}
+	globalRules := &config.AccessRules{Images: true}
+
+	router := gin.New()
+	router.Use(ListenerMiddleware(listeners, nil))
+	router.Use(ACLMiddleware(rules.NewMatcher(globalRules)))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		listener       string
+		path           string
+		expectedStatus int
+	}{
+		{"admin", "/v1.41/info", http.StatusOK},
+		{"admin", "/v1.41/images/json", http.StatusForbidden},
+		{"monitoring", "/v1.41/containers/json", http.StatusOK},
+		{"monitoring", "/v1.41/info", http.StatusForbidden},
+		{"dashboard", "/v1.41/images/json", http.StatusOK},
+		{"dashboard", "/v1.41/containers/json", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req = req.WithContext(identity.WithListener(req.Context(), tt.listener))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expectedStatus {
+			t.Errorf("For %s on %s: expected status %d, got %d", tt.path, tt.listener, tt.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestListenerRulesBindLaterPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	listeners := []*config.Listener{
+		{Name: "monitoring", Socket: "/run/monitoring.sock", AccessRules: &config.AccessRules{Containers: true}},
+	}
+	upstreams := []*config.Upstream{
+		{Name: "rootless", PathPrefix: "/rootless", AccessRules: &config.AccessRules{Containers: true, Images: true, Post: true}},
+	}
+	profiles := []*config.Profile{
+		{Name: "admin", Tokens: []string{"admin-token"}, AccessRules: &config.AccessRules{Containers: true, Images: true, Post: true}},
+	}
+
+	router := gin.New()
+	router.Use(ListenerMiddleware(listeners, nil))
+	router.Use(UpstreamMiddleware(upstreams, nil, logrus.New()))
+	router.Use(ProfileMiddleware(profiles, nil, logrus.New()))
+	router.Use(ACLMiddleware(rules.NewMatcher(&config.AccessRules{})))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name           string
+		listener       string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"Prefixed upstream on the restricted listener", "monitoring", "GET", "/rootless/v1.41/images/json", "", http.StatusForbidden},
+		{"Upstream write on the restricted listener", "monitoring", "POST", "/rootless/v1.41/containers/web/stop", "", http.StatusForbidden},
+		{"Upstream read allowed by both", "monitoring", "GET", "/rootless/v1.41/containers/json", "", http.StatusOK},
+		{"Profile token on the restricted listener", "monitoring", "GET", "/v1.41/images/json", "admin-token", http.StatusForbidden},
+		{"Prefixed upstream on another listener", "admin", "GET", "/rootless/v1.41/images/json", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req = req.WithContext(identity.WithListener(req.Context(), tt.listener))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestListenerRulesApplyToFilteredCreations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	global := &filters.AdvancedFilter{}
+	listeners := []*config.Listener{
+		{Name: "monitoring", Socket: "/run/monitoring.sock", AccessRules: &config.AccessRules{Containers: true}},
+		{Name: "admin", Socket: "/run/admin.sock", AccessRules: &config.AccessRules{Containers: true, Post: true}},
+	}
+
+	router := gin.New()
+	router.Use(ListenerMiddleware(listeners, global))
+	router.Use(AdvancedFilterMiddleware(global, nil, logrus.New()))
+	router.Use(ACLMiddleware(rules.NewMatcher(&config.AccessRules{})))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		listener       string
+		expectedStatus int
+	}{
+		{"monitoring", http.StatusForbidden},
+		{"admin", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		body := `{"Image":"nginx","HostConfig":{"Privileged":true}}`
+		req := httptest.NewRequest("POST", "/v1.43/containers/create", strings.NewReader(body))
+		req = req.WithContext(identity.WithListener(req.Context(), tt.listener))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expectedStatus {
+			t.Errorf("On listener %s: expected status %d, got %d", tt.listener, tt.expectedStatus, w.Code)
+		}
+	}
+}
@@ -3,10 +3,15 @@ package middleware
 import (
 	"time"
 
+	"dockershield/internal/identity"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// StatusClientClosedRequest is set by the proxy when the client disconnects before Docker answers
+const StatusClientClosedRequest = 499
+
 // LoggingMiddleware creates a middleware for structured logging
 func LoggingMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -26,8 +31,26 @@ func LoggingMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 			"client_ip":  c.ClientIP(),
 			"user_agent": c.Request.UserAgent(),
 		})
+		if upstream := c.GetString(UpstreamKey); upstream != "" {
+			entry = entry.WithField("upstream", upstream)
+		}
+		if profile := c.GetString(ProfileKey); profile != "" {
+			entry = entry.WithField("profile", profile)
+		}
+		if peer := identity.Peer(c.Request.Context()); peer != nil {
+			entry = entry.WithFields(logrus.Fields{
+				"peer_pid": peer.PID,
+				"peer_uid": peer.UID,
+				"peer_gid": peer.GID,
+			})
+		}
+		if cert := identity.ClientCertificate(c.Request); cert != nil {
+			entry = entry.WithField("client_cert", cert.Subject.String())
+		}
 
-		if c.Writer.Status() >= 500 {
+		if c.Writer.Status() == StatusClientClosedRequest {
+			entry.Info("Request cancelled by client")
+		} else if c.Writer.Status() >= 500 {
 			entry.Error("Request failed")
 		} else if c.Writer.Status() >= 400 {
 			entry.Warn("Client error")
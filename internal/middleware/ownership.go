@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"dockershield/pkg/filters"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// objectPathPattern matches the per-object endpoints, with an optional API version:
+// the object kind, its ID or name, and the operation
+var objectPathPattern = regexp.MustCompile(`^(?:/v[0-9.]+)?/(containers|volumes|networks)/([^/]+)(/.*)?$`)
+
+// commitPathPattern matches docker commit, whose container is a query parameter
+var commitPathPattern = regexp.MustCompile(`^(?:/v[0-9.]+)?/commit$`)
+
+// collectionEndpoints are the path segments that name an endpoint, not an object
+var collectionEndpoints = map[string]bool{
+	"json":   true,
+	"create": true,
+	"prune":  true,
+}
+
+// OwnershipMiddleware restricts the operations on existing containers, volumes
+// and networks to the ones carrying the caller's ownership labels. The target
+// object is inspected upstream before the request is forwarded. It runs after
+// the ACL, so that denied requests do not reach the daemon.
+func OwnershipMiddleware(defaultFilter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if inspector == nil {
+			c.Next()
+			return
+		}
+
+		// The ownership scopes of every selected policy apply
+		for _, filter := range filtersFor(c, defaultFilter) {
+			if filter.OwnershipEnabled() && !checkRequestOwnership(c, filter, inspector, logger) {
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// checkRequestOwnership checks the objects a request operates on against one ownership scope
+func checkRequestOwnership(c *gin.Context, filter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) bool {
+	path := c.Request.URL.Path
+
+	if commitPathPattern.MatchString(path) {
+		container := c.Query("container")
+		return container == "" || checkOwnership(c, filter, inspector, "container", container, logger)
+	}
+
+	match := objectPathPattern.FindStringSubmatch(path)
+	if match == nil {
+		return true
+	}
+
+	kind := strings.TrimSuffix(match[1], "s")
+	id, operation := match[2], match[3]
+
+	if collectionEndpoints[id] && operation == "" {
+		// Prune removes every unused object unless filtered on the caller's labels
+		if id == "prune" {
+			if allowed, reason := filter.CheckPrune(kind, c.Query("filters")); !allowed {
+				logger.Warnf("Prune denied: %s", reason)
+				denyRequest(c, "Prune denied by ownership filter", reason)
+				return false
+			}
+		}
+		// A new container can reach the volumes and namespaces of existing objects
+		if kind == "container" && id == "create" && c.Request.Method == http.MethodPost {
+			return checkCreateReferences(c, filter, inspector, logger)
+		}
+		return true
+	}
+
+	if !checkOwnership(c, filter, inspector, kind, id, logger) {
+		return false
+	}
+
+	// Connecting a container to a network also operates on the container
+	if kind == "network" && c.Request.Method == http.MethodPost && (operation == "/connect" || operation == "/disconnect") {
+		body, ok := readJSONBody(c)
+		if !ok {
+			return false
+		}
+		if container, _ := body["Container"].(string); container != "" && !checkOwnership(c, filter, inspector, "container", container, logger) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkCreateReferences checks the containers and volumes used by a container creation
+func checkCreateReferences(c *gin.Context, filter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) bool {
+	body, ok := readJSONBody(c)
+	if !ok {
+		return false
+	}
+
+	for _, ref := range filters.ContainerCreateReferences(body) {
+		labels, found, ok := inspectObjectLabels(c, inspector, ref.Kind, ref.ID, logger)
+		if !ok {
+			return false
+		}
+		// Docker creates missing named volumes on the fly: they belong to nobody else
+		if !found && ref.Kind == "volume" {
+			continue
+		}
+		if !checkLabels(c, filter, ref.Kind, ref.ID, labels, found, logger) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkOwnership inspects an object and denies the request when the caller does not own it
+func checkOwnership(c *gin.Context, filter *filters.AdvancedFilter, inspector Inspector, kind, id string, logger *logrus.Logger) bool {
+	labels, found, ok := inspectObjectLabels(c, inspector, kind, id, logger)
+	return ok && checkLabels(c, filter, kind, id, labels, found, logger)
+}
+
+// checkLabels denies the request when the object is missing or not owned by the caller
+func checkLabels(c *gin.Context, filter *filters.AdvancedFilter, kind, id string, labels map[string]string, found bool, logger *logrus.Logger) bool {
+	if !found {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "No such " + kind + ": " + id})
+		return false
+	}
+
+	if allowed, reason := filter.CheckOwnership(kind, labels); !allowed {
+		logger.Warnf("Operation on %s %s denied: %s", kind, id, reason)
+		denyRequest(c, "Operation denied by ownership filter", reason)
+		return false
+	}
+
+	return true
+}
+
+// inspectObjectLabels returns the labels of a container, volume or network, and
+// whether it exists. Inspection failures are answered with 502.
+func inspectObjectLabels(c *gin.Context, inspector Inspector, kind, id string, logger *logrus.Logger) (map[string]string, bool, bool) {
+	var inspect struct {
+		Labels map[string]string `json:"Labels"`
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"Config"`
+	}
+
+	path := "/" + kind + "s/" + id
+	if kind == "container" {
+		path += "/json"
+	}
+
+	status, err := inspector.Inspect(c, path, &inspect)
+	switch {
+	case err != nil:
+		logger.Errorf("Failed to inspect %s %s: %v", kind, id, err)
+		c.JSON(http.StatusBadGateway, gin.H{"message": "failed to inspect " + kind + ": " + id})
+	case status == http.StatusNotFound:
+		return nil, false, true
+	case status != http.StatusOK:
+		logger.Errorf("Failed to inspect %s %s: docker answered %d", kind, id, status)
+		c.JSON(http.StatusBadGateway, gin.H{"message": "failed to inspect " + kind + ": " + id})
+	default:
+		// Containers hold their labels in Config, volumes and networks at the top level
+		if kind == "container" {
+			return inspect.Config.Labels, true, true
+		}
+		return inspect.Labels, true, true
+	}
+
+	c.Abort()
+	return nil, false, false
+}
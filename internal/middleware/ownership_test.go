@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dockershield/pkg/filters"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeInspector answers inspect requests from canned JSON documents
+type fakeInspector struct {
+	objects   map[string]string
+	inspected []string
+}
+
+func (f *fakeInspector) Inspect(c *gin.Context, path string, v interface{}) (int, error) {
+	f.inspected = append(f.inspected, path)
+	body, ok := f.objects[path]
+	if !ok {
+		return http.StatusNotFound, nil
+	}
+	return http.StatusOK, json.Unmarshal([]byte(body), v)
+}
+
+func TestOwnershipMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	filter := &filters.AdvancedFilter{
+		Ownership: &filters.OwnershipFilter{Labels: map[string]string{"com.example.owner": "ci"}},
+	}
+	inspector := &fakeInspector{objects: map[string]string{
+		"/containers/mine/json":   `{"Config":{"Labels":{"com.example.owner":"ci"}}}`,
+		"/containers/theirs/json": `{"Config":{"Labels":{"com.example.owner":"prod"}}}`,
+		"/volumes/mine":           `{"Labels":{"com.example.owner":"ci"}}`,
+		"/volumes/theirs":         `{"Labels":null}`,
+		"/networks/mine":          `{"Labels":{"com.example.owner":"ci"}}`,
+	}}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Stop owned container", "POST", "/v1.43/containers/mine/stop", ``, http.StatusOK},
+		{"Stop other container", "POST", "/v1.43/containers/theirs/stop", ``, http.StatusForbidden},
+		{"Delete other container", "DELETE", "/containers/theirs", ``, http.StatusForbidden},
+		{"Logs of other container", "GET", "/v1.43/containers/theirs/logs", ``, http.StatusForbidden},
+		{"Missing container", "POST", "/v1.43/containers/gone/kill", ``, http.StatusNotFound},
+		{"List containers", "GET", "/v1.43/containers/json", ``, http.StatusOK},
+		{"Create container", "POST", "/v1.43/containers/create", `{}`, http.StatusOK},
+		{"Create with volumes of owned container", "POST", "/v1.43/containers/create", `{"HostConfig":{"VolumesFrom":["mine:ro"]}}`, http.StatusOK},
+		{"Create with volumes of other container", "POST", "/v1.43/containers/create", `{"HostConfig":{"VolumesFrom":["theirs:ro"]}}`, http.StatusForbidden},
+		{"Create binding owned volume", "POST", "/v1.43/containers/create", `{"HostConfig":{"Binds":["mine:/data"]}}`, http.StatusOK},
+		{"Create binding other volume", "POST", "/v1.43/containers/create", `{"HostConfig":{"Binds":["theirs:/data"]}}`, http.StatusForbidden},
+		{"Create binding new volume", "POST", "/v1.43/containers/create", `{"HostConfig":{"Binds":["fresh:/data"]}}`, http.StatusOK},
+		{"Create mounting other volume", "POST", "/v1.43/containers/create", `{"HostConfig":{"Mounts":[{"Type":"volume","Source":"theirs","Target":"/data"}]}}`, http.StatusForbidden},
+		{"Create in owned network namespace", "POST", "/v1.43/containers/create", `{"HostConfig":{"NetworkMode":"container:mine"}}`, http.StatusOK},
+		{"Create in other network namespace", "POST", "/v1.43/containers/create", `{"HostConfig":{"NetworkMode":"container:theirs"}}`, http.StatusForbidden},
+		{"Create in other PID namespace", "POST", "/v1.43/containers/create", `{"HostConfig":{"PidMode":"container:theirs"}}`, http.StatusForbidden},
+		{"Create in other IPC namespace", "POST", "/v1.43/containers/create", `{"HostConfig":{"IpcMode":"container:theirs"}}`, http.StatusForbidden},
+		{"Create in missing container namespace", "POST", "/v1.43/containers/create", `{"HostConfig":{"IpcMode":"container:gone"}}`, http.StatusNotFound},
+		{"Unfiltered prune", "POST", "/v1.43/containers/prune", ``, http.StatusForbidden},
+		{"Prune owned containers", "POST", `/v1.43/containers/prune?filters={"label":["com.example.owner=ci"]}`, ``, http.StatusOK},
+		{"Remove owned volume", "DELETE", "/v1.43/volumes/mine", ``, http.StatusOK},
+		{"Remove other volume", "DELETE", "/v1.43/volumes/theirs", ``, http.StatusForbidden},
+		{"Connect owned container", "POST", "/v1.43/networks/mine/connect", `{"Container":"mine"}`, http.StatusOK},
+		{"Connect other container", "POST", "/v1.43/networks/mine/connect", `{"Container":"theirs"}`, http.StatusForbidden},
+		{"Commit other container", "POST", "/v1.43/commit?container=theirs", ``, http.StatusForbidden},
+		{"Image inspect", "GET", "/v1.43/images/nginx/json", ``, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(OwnershipMiddleware(filter, inspector, logrus.New()))
+			router.Any("/*path", func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("Policy without ownership", func(t *testing.T) {
+		inspector.inspected = nil
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			AddPolicy(c, &Policy{Name: "admin", Filter: &filters.AdvancedFilter{}})
+		})
+		router.Use(OwnershipMiddleware(filter, inspector, logrus.New()))
+		router.Any("/*path", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest("POST", "/v1.43/containers/theirs/stop", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || len(inspector.inspected) != 0 {
+			t.Errorf("Expected the request to pass without inspection, got %d after %v", w.Code, inspector.inspected)
+		}
+	})
+}
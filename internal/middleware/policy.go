@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"dockershield/pkg/filters"
+	"dockershield/pkg/rules"
+
+	"github.com/gin-gonic/gin"
+)
+
+// policyKey is the context key of the policies selected for the request
+const policyKey = "policy"
+
+// Policy groups the access rules and advanced filters applied to a request.
+// The listener, upstream and profile policies selected for a request are all
+// enforced: a request must be allowed by each of them, so a later policy can
+// restrict an earlier one but never widen it. A nil Matcher leaves the access
+// rules to the other policies, or to the global rules when none has any.
+type Policy struct {
+	Name    string
+	Matcher *rules.Matcher
+	Filter  *filters.AdvancedFilter
+}
+
+// AddPolicy adds a policy to the ones enforced by the ACL and advanced filter middlewares
+func AddPolicy(c *gin.Context, policy *Policy) {
+	c.Set(policyKey, append(Policies(c), policy))
+}
+
+// Policies returns the policies selected for the request, from the listener to the profile
+func Policies(c *gin.Context) []*Policy {
+	if value, exists := c.Get(policyKey); exists {
+		if policies, ok := value.([]*Policy); ok {
+			return policies
+		}
+	}
+	return nil
+}
+
+// GetPolicy returns the most specific policy selected for the request, or nil
+func GetPolicy(c *gin.Context) *Policy {
+	policies := Policies(c)
+	if len(policies) == 0 {
+		return nil
+	}
+	return policies[len(policies)-1]
+}
+
+// filtersFor returns the advanced filters of the policies selected for the
+// request, or the global ones. Each filter is returned once.
+func filtersFor(c *gin.Context, defaultFilter *filters.AdvancedFilter) []*filters.AdvancedFilter {
+	var selected []*filters.AdvancedFilter
+	for _, policy := range Policies(c) {
+		if policy.Filter != nil && !containsFilter(selected, policy.Filter) {
+			selected = append(selected, policy.Filter)
+		}
+	}
+	if len(selected) == 0 && defaultFilter != nil {
+		selected = append(selected, defaultFilter)
+	}
+	return selected
+}
+
+// containsFilter reports whether a filter is already in the list
+func containsFilter(list []*filters.AdvancedFilter, filter *filters.AdvancedFilter) bool {
+	for _, item := range list {
+		if item == filter {
+			return true
+		}
+	}
+	return false
+}
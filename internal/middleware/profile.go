@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"slices"
+	"strings"
+
+	"dockershield/config"
+	"dockershield/internal/identity"
+	"dockershield/pkg/filters"
+	"dockershield/pkg/rules"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ProfileKey is the context key holding the name of the caller's profile
+const ProfileKey = "profile"
+
+// tokenHashPrefix marks a token stored as its SHA-256 digest
+const tokenHashPrefix = "sha256:"
+
+// profileRule is a profile with its parsed selectors
+type profileRule struct {
+	profile  *config.Profile
+	networks []*net.IPNet
+	policy   *Policy
+}
+
+// ProfileMiddleware identifies the caller and applies the access rules and
+// advanced filters of the first profile whose selectors all match. A matching
+// profile narrows the rules of the listener and the selected upstream: a request
+// must be allowed by all of them. A profile without its own filters keeps
+// defaultFilter.
+func ProfileMiddleware(profiles []*config.Profile, defaultFilter *filters.AdvancedFilter, logger *logrus.Logger) gin.HandlerFunc {
+	profileRules := make([]*profileRule, 0, len(profiles))
+	for _, profile := range profiles {
+		profileRules = append(profileRules, newProfileRule(profile, defaultFilter, logger))
+	}
+
+	return func(c *gin.Context) {
+		token := identity.BearerToken(c.Request)
+
+		for _, rule := range profileRules {
+			if !rule.matches(c, token) {
+				continue
+			}
+
+			// The token authenticates the caller to the proxy, not to Docker
+			if len(rule.profile.Tokens) > 0 {
+				c.Request.Header.Del("Authorization")
+			}
+			c.Set(ProfileKey, rule.profile.Name)
+			AddPolicy(c, rule.policy)
+			break
+		}
+
+		c.Next()
+	}
+}
+
+func newProfileRule(profile *config.Profile, defaultFilter *filters.AdvancedFilter, logger *logrus.Logger) *profileRule {
+	policy := &Policy{
+		Name:    profile.Name,
+		Matcher: rules.NewMatcher(profile.AccessRules),
+		Filter:  profile.AdvancedFilters,
+	}
+	if policy.Filter == nil {
+		policy.Filter = defaultFilter
+	}
+
+	return &profileRule{
+		profile:  profile,
+		networks: parseCIDRs(profile.ClientCIDRs, logger),
+		policy:   policy,
+	}
+}
+
+// matches reports whether the caller satisfies every selector of the profile
+func (r *profileRule) matches(c *gin.Context, token string) bool {
+	if len(r.profile.CertSubjects) > 0 && !certSubjectMatches(c, r.profile.CertSubjects) {
+		return false
+	}
+
+	if len(r.profile.Tokens) > 0 && !tokenMatches(token, r.profile.Tokens) {
+		return false
+	}
+
+	if len(r.profile.ClientCIDRs) > 0 && !ipInNetworks(c.RemoteIP(), r.networks) {
+		return false
+	}
+
+	if len(r.profile.UIDs) > 0 || len(r.profile.GIDs) > 0 {
+		peer := identity.Peer(c.Request.Context())
+		if peer == nil {
+			return false
+		}
+		if len(r.profile.UIDs) > 0 && !slices.Contains(r.profile.UIDs, peer.UID) {
+			return false
+		}
+		if len(r.profile.GIDs) > 0 && !slices.Contains(r.profile.GIDs, peer.GID) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// certSubjectMatches checks the verified client certificate against the
+// expected subjects, given as a common name or a full distinguished name
+func certSubjectMatches(c *gin.Context, subjects []string) bool {
+	cert := identity.ClientCertificate(c.Request)
+	if cert == nil {
+		return false
+	}
+	return containsString(subjects, cert.Subject.CommonName) || containsString(subjects, cert.Subject.String())
+}
+
+// tokenMatches compares the caller token with the profile tokens in constant time
+func tokenMatches(token string, tokens []string) bool {
+	if token == "" {
+		return false
+	}
+
+	digest := sha256.Sum256([]byte(token))
+	hexDigest := hex.EncodeToString(digest[:])
+
+	matched := false
+	for _, expected := range tokens {
+		candidate := token
+		if strings.HasPrefix(expected, tokenHashPrefix) {
+			expected = strings.ToLower(strings.TrimPrefix(expected, tokenHashPrefix))
+			candidate = hexDigest
+		}
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(expected)) == 1 {
+			matched = true
+		}
+	}
+	return matched
+}
@@ -0,0 +1,274 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dockershield/config"
+	"dockershield/internal/identity"
+	"dockershield/pkg/filters"
+	"dockershield/pkg/rules"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// withClientCert emulates a request authenticated by mutual TLS
+func withClientCert(req *http.Request, commonName string) *http.Request {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName, Organization: []string{"ops"}}}
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+	return req
+}
+
+func TestProfileMiddlewareSelection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ciDigest := sha256.Sum256([]byte("ci-token"))
+	profiles := []*config.Profile{
+		{Name: "traefik", CertSubjects: []string{"traefik"}, AccessRules: &config.AccessRules{Containers: true}},
+		{Name: "portainer", CertSubjects: []string{"CN=portainer,O=ops"}, AccessRules: &config.AccessRules{Containers: true}},
+		{Name: "ci", Tokens: []string{"sha256:" + hex.EncodeToString(ciDigest[:])}, ClientCIDRs: []string{"10.1.0.0/16"}, AccessRules: &config.AccessRules{Images: true}},
+		{Name: "deploy", Tokens: []string{"deploy-token"}, AccessRules: &config.AccessRules{Services: true}},
+	}
+
+	tests := []struct {
+		name            string
+		commonName      string
+		authorization   string
+		remoteAddr      string
+		expectedProfile string
+	}{
+		{"Certificate common name", "traefik", "", "127.0.0.1:5000", "traefik"},
+		{"Certificate full subject", "portainer", "", "127.0.0.1:5000", "portainer"},
+		{"Unknown certificate", "mallory", "", "127.0.0.1:5000", ""},
+		{"Hashed token from allowed network", "", "Bearer ci-token", "10.1.2.3:5000", "ci"},
+		{"Hashed token from other network", "", "Bearer ci-token", "172.17.0.2:5000", ""},
+		{"Plain token", "", "bearer deploy-token", "172.17.0.2:5000", "deploy"},
+		{"Wrong token", "", "Bearer guess", "10.1.2.3:5000", ""},
+		{"Anonymous caller", "", "", "10.1.2.3:5000", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var profile, authorization string
+			var policy *Policy
+
+			router := gin.New()
+			router.Use(ProfileMiddleware(profiles, nil, logrus.New()))
+			router.Any("/*path", func(c *gin.Context) {
+				profile = c.GetString(ProfileKey)
+				policy = GetPolicy(c)
+				authorization = c.Request.Header.Get("Authorization")
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/v1.41/containers/json", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.commonName != "" {
+				req = withClientCert(req, tt.commonName)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if profile != tt.expectedProfile {
+				t.Errorf("Expected profile '%s', got '%s'", tt.expectedProfile, profile)
+			}
+			if tt.expectedProfile != "" && (policy == nil || policy.Name != tt.expectedProfile) {
+				t.Errorf("Expected policy of profile '%s'", tt.expectedProfile)
+			}
+			if tt.expectedProfile == "" && policy != nil {
+				t.Errorf("Expected no policy, got '%s'", policy.Name)
+			}
+			if tt.expectedProfile != "" && tt.authorization != "" && authorization != "" {
+				t.Error("Expected the proxy token not to be forwarded to Docker")
+			}
+		})
+	}
+}
+
+func TestProfileMiddlewareRestrictsUpstreamRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstreams := []*config.Upstream{
+		{Name: "rootless", PathPrefix: "/rootless", AccessRules: &config.AccessRules{Containers: true, Images: true}},
+	}
+	profiles := []*config.Profile{
+		{Name: "ci", Tokens: []string{"ci-token"}, AccessRules: &config.AccessRules{Images: true}},
+	}
+
+	router := gin.New()
+	router.Use(UpstreamMiddleware(upstreams, nil, logrus.New()))
+	router.Use(ProfileMiddleware(profiles, nil, logrus.New()))
+	router.Use(ACLMiddleware(rules.NewMatcher(&config.AccessRules{})))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"/rootless/v1.41/containers/json", "", http.StatusOK},
+		{"/rootless/v1.41/containers/json", "ci-token", http.StatusForbidden},
+		{"/rootless/v1.41/images/json", "ci-token", http.StatusOK},
+		{"/rootless/v1.41/info", "ci-token", http.StatusForbidden},
+		{"/v1.41/images/json", "", http.StatusForbidden},
+		{"/v1.41/images/json", "ci-token", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expectedStatus {
+			t.Errorf("For path %s (token %q): expected status %d, got %d", tt.path, tt.token, tt.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestProfileRulesApplyToFilteredCreations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	global := &filters.AdvancedFilter{}
+	profiles := []*config.Profile{
+		{Name: "monitoring", Tokens: []string{"monitoring-token"}, AccessRules: &config.AccessRules{Containers: true}},
+		{Name: "ci", Tokens: []string{"ci-token"}, AccessRules: &config.AccessRules{Containers: true, Images: true, Build: true, Post: true}},
+	}
+
+	router := gin.New()
+	router.Use(ProfileMiddleware(profiles, global, logrus.New()))
+	router.Use(AdvancedFilterMiddleware(global, nil, logrus.New()))
+	router.Use(ACLMiddleware(rules.NewMatcher(&config.AccessRules{})))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		// The filter authorization only waives the global rules
+		{"/v1.43/containers/create", "", http.StatusOK},
+		{"/v1.43/containers/create", "monitoring-token", http.StatusForbidden},
+		{"/v1.43/images/create?fromImage=alpine", "monitoring-token", http.StatusForbidden},
+		{"/v1.43/build?t=app:1.0", "monitoring-token", http.StatusForbidden},
+		{"/v1.43/containers/abc/start", "monitoring-token", http.StatusForbidden},
+		{"/v1.43/containers/create", "ci-token", http.StatusOK},
+		{"/v1.43/images/create?fromImage=alpine", "ci-token", http.StatusOK},
+		{"/v1.43/build?t=app:1.0", "ci-token", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(`{"Image":"nginx"}`))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expectedStatus {
+			t.Errorf("For path %s (token %q): expected status %d, got %d", tt.path, tt.token, tt.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestProfileMiddlewareKeepsGlobalFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	global := &filters.AdvancedFilter{Containers: &filters.ContainerFilter{DenyPrivileged: true}}
+	upstreams := []*config.Upstream{
+		{Name: "rootless", PathPrefix: "/rootless", AdvancedFilters: &filters.AdvancedFilter{}},
+	}
+	profiles := []*config.Profile{
+		{Name: "ci", Tokens: []string{"ci-token"}},
+	}
+
+	router := gin.New()
+	router.Use(UpstreamMiddleware(upstreams, nil, logrus.New()))
+	router.Use(ProfileMiddleware(profiles, global, logrus.New()))
+	router.Use(AdvancedFilterMiddleware(global, nil, logrus.New()))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		token          string
+		expectedStatus int
+	}{
+		{"", http.StatusOK},
+		{"ci-token", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		body := `{"Image":"nginx","HostConfig":{"Privileged":true}}`
+		req := httptest.NewRequest("POST", "/rootless/v1.41/containers/create", strings.NewReader(body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.expectedStatus {
+			t.Errorf("With token %q: expected status %d, got %d", tt.token, tt.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestProfileMiddlewarePeerCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	profiles := []*config.Profile{
+		{Name: "monitoring", UIDs: []uint32{1001}, AccessRules: &config.AccessRules{Containers: true}},
+		{Name: "deployers", UIDs: []uint32{1002, 1003}, GIDs: []uint32{2000}, AccessRules: &config.AccessRules{Services: true}},
+	}
+
+	tests := []struct {
+		name            string
+		peer            *identity.PeerCredentials
+		expectedProfile string
+	}{
+		{"Matching user", &identity.PeerCredentials{PID: 42, UID: 1001, GID: 1001}, "monitoring"},
+		{"Matching user and group", &identity.PeerCredentials{PID: 42, UID: 1003, GID: 2000}, "deployers"},
+		{"Matching user, other group", &identity.PeerCredentials{PID: 42, UID: 1003, GID: 1003}, ""},
+		{"Unknown user", &identity.PeerCredentials{PID: 42, UID: 0, GID: 0}, ""},
+		{"TCP caller", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var profile string
+
+			router := gin.New()
+			router.Use(ProfileMiddleware(profiles, nil, logrus.New()))
+			router.Any("/*path", func(c *gin.Context) {
+				profile = c.GetString(ProfileKey)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/v1.41/containers/json", nil)
+			if tt.peer != nil {
+				req = req.WithContext(identity.WithPeer(req.Context(), tt.peer))
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if profile != tt.expectedProfile {
+				t.Errorf("Expected profile '%s', got '%s'", tt.expectedProfile, profile)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"dockershield/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutClass identifies the timeout profile applied to a request
+type TimeoutClass int
+
+const (
+	// TimeoutShort applies to inspect, list and other read-only calls
+	TimeoutShort TimeoutClass = iota
+	// TimeoutDefault applies to the other non-streaming calls
+	TimeoutDefault
+	// TimeoutStream applies to calls streaming data for an unbounded time
+	TimeoutStream
+)
+
+// streamEndpoints are always streaming, whatever the query parameters
+var streamEndpoints = []*regexp.Regexp{
+	regexp.MustCompile(`^(/v[\d.]+)?/events$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/containers/[^/]+/(attach|attach/ws|wait|export|archive)$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/(containers|services|tasks)/[^/]+/logs$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/commit$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/exec/[^/]+/start$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/images/(create|load|get)$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/images/.+/(push|get)$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/(build|session|grpc)$`),
+	regexp.MustCompile(`^(/v[\d.]+)?/plugins/(pull|.+/upgrade|.+/push)$`),
+}
+
+// connDeadlineGrace is the extra time allowed to report a timeout to the client
+const connDeadlineGrace = 5 * time.Second
+
+var (
+	statsEndpoint = regexp.MustCompile(`^(/v[\d.]+)?/containers/[^/]+/stats$`)
+	stopEndpoint  = regexp.MustCompile(`^(/v[\d.]+)?/containers/[^/]+/(stop|restart)$`)
+)
+
+// ClassifyRequest selects the timeout profile from the request method and path.
+// short is the deadline of the short profile, which a stop grace period may exceed.
+func ClassifyRequest(r *http.Request, short time.Duration) TimeoutClass {
+	path := r.URL.Path
+	query := r.URL.Query()
+
+	for _, pattern := range streamEndpoints {
+		if pattern.MatchString(path) {
+			return TimeoutStream
+		}
+	}
+
+	// stats stream unless stream=0
+	if statsEndpoint.MatchString(path) && (query.Get("stream") == "" || isTrueParam(query.Get("stream"))) {
+		return TimeoutStream
+	}
+
+	// stop and restart wait up to t seconds (-1 = forever) before killing the container
+	if stopEndpoint.MatchString(path) && stopWaitExceeds(query.Get("t"), short) {
+		return TimeoutStream
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return TimeoutShort
+	}
+	return TimeoutDefault
+}
+
+// stopWaitExceeds reports whether the stop timeout t (in seconds) is longer than short
+func stopWaitExceeds(t string, short time.Duration) bool {
+	seconds, err := strconv.Atoi(t)
+	if err != nil {
+		return false
+	}
+	return seconds < 0 || (short > 0 && time.Duration(seconds)*time.Second > short)
+}
+
+// isTrueParam reports whether a Docker boolean query parameter is enabled
+func isTrueParam(value string) bool {
+	return value == "1" || value == "true" || value == "True"
+}
+
+// TimeoutMiddleware applies the timeout profile of each request.
+// Short and default requests get a deadline covering the whole exchange, while
+// streaming requests are only interrupted after StreamIdle without traffic.
+func TimeoutMiddleware(timeouts *config.Timeouts) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeouts == nil {
+			c.Next()
+			return
+		}
+
+		controller := http.NewResponseController(c.Writer)
+
+		switch ClassifyRequest(c.Request, timeouts.Short) {
+		case TimeoutStream:
+			// Connection deadlines left by a previous request must not cut the stream
+			_ = controller.SetReadDeadline(time.Time{})
+			_ = controller.SetWriteDeadline(time.Time{})
+			if timeouts.StreamIdle > 0 {
+				stop := watchIdle(c, timeouts.StreamIdle)
+				defer stop()
+			}
+		case TimeoutShort:
+			cancel := applyDeadline(c, controller, timeouts.Short)
+			defer cancel()
+		default:
+			cancel := applyDeadline(c, controller, timeouts.Default)
+			defer cancel()
+		}
+
+		c.Next()
+	}
+}
+
+// applyDeadline bounds the request context and the connection I/O to the given duration
+func applyDeadline(c *gin.Context, controller *http.ResponseController, timeout time.Duration) context.CancelFunc {
+	if timeout <= 0 {
+		_ = controller.SetReadDeadline(time.Time{})
+		_ = controller.SetWriteDeadline(time.Time{})
+		return func() {}
+	}
+
+	// The connection outlives the context deadline a little so that the
+	// timeout is reported by the proxy rather than by a dropped connection
+	deadline := time.Now().Add(timeout)
+	_ = controller.SetReadDeadline(deadline.Add(connDeadlineGrace))
+	_ = controller.SetWriteDeadline(deadline.Add(connDeadlineGrace))
+
+	ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+	c.Request = c.Request.WithContext(ctx)
+	return cancel
+}
+
+// watchIdle cancels the request context once no data has been exchanged for idle.
+// The cancellation cause wraps context.DeadlineExceeded so it is reported as a timeout.
+// Hijacked connections stop the watch: the proxy relay tracks their activity itself.
+func watchIdle(c *gin.Context, idle time.Duration) func() {
+	ctx, cancel := context.WithCancelCause(c.Request.Context())
+	timer := time.AfterFunc(idle, func() {
+		cancel(fmt.Errorf("stream idle for %s: %w", idle, context.DeadlineExceeded))
+	})
+
+	activity := &idleActivity{timer: timer, idle: idle}
+	c.Request = c.Request.WithContext(ctx)
+	if c.Request.Body != nil {
+		c.Request.Body = &idleReader{ReadCloser: c.Request.Body, activity: activity}
+	}
+	c.Writer = &idleWriter{ResponseWriter: c.Writer, activity: activity}
+
+	return func() {
+		activity.stop()
+		cancel(nil)
+	}
+}
+
+// idleActivity postpones the idle timer each time data flows
+type idleActivity struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	idle    time.Duration
+	stopped bool
+}
+
+func (a *idleActivity) touch() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.stopped {
+		a.timer.Reset(a.idle)
+	}
+}
+
+func (a *idleActivity) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopped = true
+	a.timer.Stop()
+}
+
+// idleReader records activity on the request body (build contexts, image loads)
+type idleReader struct {
+	io.ReadCloser
+	activity *idleActivity
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.activity.touch()
+	}
+	return n, err
+}
+
+// idleWriter records activity on the response stream
+type idleWriter struct {
+	gin.ResponseWriter
+	activity *idleActivity
+}
+
+func (w *idleWriter) Write(data []byte) (int, error) {
+	w.activity.touch()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idleWriter) WriteString(s string) (int, error) {
+	w.activity.touch()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *idleWriter) Flush() {
+	w.activity.touch()
+	w.ResponseWriter.Flush()
+}
+
+func (w *idleWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.activity.stop()
+	return w.ResponseWriter.Hijack()
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dockershield/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestClassifyRequest(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected TimeoutClass
+	}{
+		{"GET", "/v1.41/containers/json", TimeoutShort},
+		{"GET", "/v1.41/containers/abc/json", TimeoutShort},
+		{"HEAD", "/_ping", TimeoutShort},
+		{"GET", "/v1.41/containers/abc/stats?stream=0", TimeoutShort},
+		{"POST", "/v1.41/containers/create", TimeoutDefault},
+		{"POST", "/v1.41/containers/abc/stop", TimeoutDefault},
+		{"POST", "/v1.41/containers/abc/stop?t=10", TimeoutDefault},
+		{"POST", "/v1.41/containers/abc/restart?t=30", TimeoutDefault},
+		{"POST", "/v1.41/containers/abc/stop?t=300", TimeoutStream},
+		{"POST", "/v1.41/containers/abc/restart?t=120", TimeoutStream},
+		{"POST", "/v1.41/containers/abc/stop?t=-1", TimeoutStream},
+		{"POST", "/v1.41/commit?container=abc&repo=app", TimeoutStream},
+		{"GET", "/v1.41/containers/abc/logs?stdout=1", TimeoutStream},
+		{"GET", "/v1.41/containers/abc/logs?stdout=1&tail=all", TimeoutStream},
+		{"DELETE", "/v1.41/images/nginx", TimeoutDefault},
+		{"GET", "/v1.41/events", TimeoutStream},
+		{"GET", "/events?since=0", TimeoutStream},
+		{"GET", "/v1.41/containers/abc/logs?follow=1", TimeoutStream},
+		{"GET", "/v1.41/services/web/logs?follow=true", TimeoutStream},
+		{"GET", "/v1.41/containers/abc/stats", TimeoutStream},
+		{"POST", "/v1.41/containers/abc/wait", TimeoutStream},
+		{"POST", "/v1.41/containers/abc/attach?stream=1", TimeoutStream},
+		{"POST", "/v1.41/exec/abc/start", TimeoutStream},
+		{"POST", "/v1.41/images/create?fromImage=nginx", TimeoutStream},
+		{"POST", "/v1.41/images/registry.local/app/push", TimeoutStream},
+		{"GET", "/v1.41/images/nginx/get", TimeoutStream},
+		{"POST", "/v1.41/build?t=app", TimeoutStream},
+		{"POST", "/session", TimeoutStream},
+		{"POST", "/grpc", TimeoutStream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if result := ClassifyRequest(req, 30*time.Second); result != tt.expected {
+				t.Errorf("Expected class %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddlewareDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	timeouts := &config.Timeouts{Short: 10 * time.Second, Default: time.Minute}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectDeadline time.Duration
+	}{
+		{"Short profile", "GET", "/v1.41/containers/json", 10 * time.Second},
+		{"Default profile", "POST", "/v1.41/containers/create", time.Minute},
+		{"Streaming profile", "GET", "/v1.41/events", 0},
+		{"Stop within short deadline", "POST", "/v1.41/containers/abc/stop?t=5", time.Minute},
+		{"Stop beyond short deadline", "POST", "/v1.41/containers/abc/stop?t=20", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var hasDeadline bool
+
+			router := gin.New()
+			router.Use(TimeoutMiddleware(timeouts))
+			router.Any("/*path", func(c *gin.Context) {
+				deadline, hasDeadline = c.Request.Context().Deadline()
+				c.Status(http.StatusOK)
+			})
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			if tt.expectDeadline == 0 {
+				if hasDeadline {
+					t.Errorf("Expected no deadline, got %s", time.Until(deadline))
+				}
+				return
+			}
+			if !hasDeadline {
+				t.Fatal("Expected a deadline on the request context")
+			}
+			if remaining := time.Until(deadline); remaining > tt.expectDeadline || remaining < tt.expectDeadline-time.Second {
+				t.Errorf("Expected deadline in %s, got %s", tt.expectDeadline, remaining)
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddlewareStreamIdle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	timeouts := &config.Timeouts{Short: time.Second, Default: time.Second, StreamIdle: 100 * time.Millisecond}
+
+	router := gin.New()
+	router.Use(TimeoutMiddleware(timeouts))
+	router.GET("/events", func(c *gin.Context) {
+		// Regular traffic keeps the stream alive beyond the idle timeout
+		for i := 0; i < 4; i++ {
+			time.Sleep(50 * time.Millisecond)
+			_, _ = c.Writer.Write([]byte("{}\n"))
+			c.Writer.Flush()
+		}
+		if c.Request.Context().Err() != nil {
+			t.Error("Stream was cancelled while data was flowing")
+		}
+
+		// Then the stream goes silent and must be cancelled
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(2 * time.Second):
+			t.Error("Idle stream was not cancelled")
+		}
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/events", nil))
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"dockershield/config"
+	"dockershield/internal/identity"
+	"dockershield/pkg/filters"
+	"dockershield/pkg/rules"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// UpstreamKey is the context key holding the name of the selected upstream.
+// It is unset when the request goes to the default DOCKER_SOCKET.
+const UpstreamKey = "upstream"
+
+// upstreamRoute is an upstream with its parsed selectors
+type upstreamRoute struct {
+	upstream *config.Upstream
+	networks []*net.IPNet
+	policy   *Policy
+}
+
+// UpstreamMiddleware routes each request to the first upstream whose selectors
+// all match, and applies that upstream's access rules and advanced filters.
+// An upstream without its own filters keeps defaultFilter.
+func UpstreamMiddleware(upstreams []*config.Upstream, defaultFilter *filters.AdvancedFilter, logger *logrus.Logger) gin.HandlerFunc {
+	routes := make([]*upstreamRoute, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		policy := &Policy{
+			Name:    upstream.Name,
+			Matcher: rules.NewMatcher(upstream.AccessRules),
+			Filter:  upstream.AdvancedFilters,
+		}
+		if policy.Filter == nil {
+			policy.Filter = defaultFilter
+		}
+
+		routes = append(routes, &upstreamRoute{
+			upstream: upstream,
+			networks: parseCIDRs(upstream.ClientCIDRs, logger),
+			policy:   policy,
+		})
+	}
+
+	return func(c *gin.Context) {
+		for _, route := range routes {
+			if !route.matches(c) {
+				continue
+			}
+
+			if prefix := route.upstream.PathPrefix; prefix != "" {
+				c.Request.URL.Path = "/" + strings.TrimLeft(strings.TrimPrefix(c.Request.URL.Path, prefix), "/")
+				c.Request.URL.RawPath = ""
+			}
+			c.Set(UpstreamKey, route.upstream.Name)
+			AddPolicy(c, route.policy)
+			break
+		}
+
+		c.Next()
+	}
+}
+
+// matches reports whether the request satisfies every selector of the upstream
+func (r *upstreamRoute) matches(c *gin.Context) bool {
+	if len(r.upstream.Listeners) > 0 && !containsString(r.upstream.Listeners, identity.Listener(c.Request.Context())) {
+		return false
+	}
+
+	if len(r.upstream.ClientCIDRs) > 0 && !ipInNetworks(c.RemoteIP(), r.networks) {
+		return false
+	}
+
+	if prefix := r.upstream.PathPrefix; prefix != "" {
+		path := c.Request.URL.Path
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseCIDRs parses client networks, skipping invalid entries.
+// A bare IP address is treated as a single-host network.
+func parseCIDRs(cidrs []string, logger *logrus.Logger) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			if ip := net.ParseIP(cidr); ip != nil && ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Warnf("Ignoring invalid client CIDR '%s': %v", cidr, err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// ipInNetworks reports whether the client address belongs to one of the networks.
+// Unix socket clients have no address and never match.
+func ipInNetworks(address string, networks []*net.IPNet) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString checks if a slice contains a specific string
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
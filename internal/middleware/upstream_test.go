@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dockershield/config"
+	"dockershield/internal/identity"
+	"dockershield/pkg/filters"
+	"dockershield/pkg/rules"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUpstreamMiddlewareSelection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstreams := []*config.Upstream{
+		{
+			Name:        "rootless",
+			PathPrefix:  "/rootless",
+			AccessRules: &config.AccessRules{Containers: true},
+		},
+		{
+			Name:        "ci",
+			ClientCIDRs: []string{"10.1.0.0/16", "192.168.1.10"},
+			AccessRules: &config.AccessRules{Images: true},
+		},
+		{
+			Name:        "admin",
+			Listeners:   []string{"unix:///run/admin.sock"},
+			AccessRules: &config.AccessRules{Info: true},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		path             string
+		remoteAddr       string
+		listener         string
+		expectedUpstream string
+		expectedPath     string
+	}{
+		{"Path prefix stripped", "/rootless/v1.41/containers/json", "127.0.0.1:5000", "", "rootless", "/v1.41/containers/json"},
+		{"Path prefix must match a segment", "/rootlessx/containers/json", "127.0.0.1:5000", "", "", "/rootlessx/containers/json"},
+		{"Client network", "/v1.41/images/json", "10.1.2.3:5000", "", "ci", "/v1.41/images/json"},
+		{"Single client address", "/v1.41/images/json", "192.168.1.10:5000", "", "ci", "/v1.41/images/json"},
+		{"Listener", "/v1.41/info", "", "unix:///run/admin.sock", "admin", "/v1.41/info"},
+		{"Default daemon", "/v1.41/info", "172.17.0.2:5000", ":2375", "", "/v1.41/info"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upstream, path string
+			var policy *Policy
+
+			router := gin.New()
+			router.Use(UpstreamMiddleware(upstreams, nil, logrus.New()))
+			router.Any("/*path", func(c *gin.Context) {
+				upstream = c.GetString(UpstreamKey)
+				path = c.Request.URL.Path
+				policy = GetPolicy(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			req = req.WithContext(identity.WithListener(req.Context(), tt.listener))
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if upstream != tt.expectedUpstream {
+				t.Errorf("Expected upstream '%s', got '%s'", tt.expectedUpstream, upstream)
+			}
+			if path != tt.expectedPath {
+				t.Errorf("Expected forwarded path '%s', got '%s'", tt.expectedPath, path)
+			}
+			if tt.expectedUpstream == "" && policy != nil {
+				t.Errorf("Expected no policy for the default daemon, got '%s'", policy.Name)
+			}
+			if tt.expectedUpstream != "" && (policy == nil || policy.Name != tt.expectedUpstream) {
+				t.Errorf("Expected policy of upstream '%s'", tt.expectedUpstream)
+			}
+		})
+	}
+}
+
+func TestUpstreamMiddlewareAppliesUpstreamRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstreams := []*config.Upstream{
+		{
+			Name:        "rootless",
+			PathPrefix:  "/rootless",
+			AccessRules: &config.AccessRules{Containers: true},
+		},
+	}
+	globalRules := &config.AccessRules{Containers: false, Images: true}
+
+	router := gin.New()
+	router.Use(UpstreamMiddleware(upstreams, nil, logrus.New()))
+	router.Use(ACLMiddleware(rules.NewMatcher(globalRules)))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/rootless/v1.41/containers/json", http.StatusOK},
+		{"/rootless/v1.41/images/json", http.StatusForbidden},
+		{"/v1.41/containers/json", http.StatusForbidden},
+		{"/v1.41/images/json", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.expectedStatus {
+			t.Errorf("For path %s: expected status %d, got %d", tt.path, tt.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestUpstreamMiddlewareKeepsGlobalFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	global := &filters.AdvancedFilter{Containers: &filters.ContainerFilter{DenyPrivileged: true}}
+	upstreams := []*config.Upstream{
+		{Name: "rootless", PathPrefix: "/rootless"},
+		{Name: "builder", PathPrefix: "/builder", AdvancedFilters: &filters.AdvancedFilter{}},
+	}
+
+	router := gin.New()
+	router.Use(UpstreamMiddleware(upstreams, global, logrus.New()))
+	router.Use(AdvancedFilterMiddleware(global, nil, logrus.New()))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/rootless/v1.41/containers/create", http.StatusForbidden},
+		{"/builder/v1.41/containers/create", http.StatusOK},
+		{"/v1.41/containers/create", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		body := `{"Image":"nginx","HostConfig":{"Privileged":true}}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, strings.NewReader(body)))
+		if w.Code != tt.expectedStatus {
+			t.Errorf("For path %s: expected status %d, got %d", tt.path, tt.expectedStatus, w.Code)
+		}
+	}
+}
+
+func TestUpstreamRulesApplyToFilteredCreations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	global := &filters.AdvancedFilter{}
+	upstreams := []*config.Upstream{
+		{Name: "rootless", PathPrefix: "/rootless", AccessRules: &config.AccessRules{Containers: true, Post: true}},
+	}
+
+	router := gin.New()
+	router.Use(UpstreamMiddleware(upstreams, global, logrus.New()))
+	router.Use(AdvancedFilterMiddleware(global, nil, logrus.New()))
+	router.Use(ACLMiddleware(rules.NewMatcher(&config.AccessRules{})))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/rootless/v1.43/containers/create", http.StatusOK},
+		{"/rootless/v1.43/images/create?fromImage=alpine", http.StatusForbidden},
+		{"/rootless/v1.43/build?t=app:1.0", http.StatusForbidden},
+		{"/v1.43/build?t=app:1.0", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, strings.NewReader(`{"Image":"nginx"}`)))
+		if w.Code != tt.expectedStatus {
+			t.Errorf("For path %s: expected status %d, got %d", tt.path, tt.expectedStatus, w.Code)
+		}
+	}
+}
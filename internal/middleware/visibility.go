@@ -0,0 +1,405 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"dockershield/pkg/filters"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Endpoints whose answer is filtered, with an optional API version
+var (
+	listPathPattern = regexp.MustCompile(`^(?:/v[0-9.]+)?/(containers/json|images/json|volumes|networks|system/df)$`)
+
+	// Keys of the object arrays in the list answers wrapped in an object
+	listWrappers = map[string][]string{
+		"volumes":   {"Volumes"},
+		"system/df": {"Images", "Containers", "Volumes"},
+	}
+
+	inspectPathPatterns = map[string]*regexp.Regexp{
+		"container": regexp.MustCompile(`^(?:/v[0-9.]+)?/containers/([^/]+)/json$`),
+		// Image names may contain slashes (registry.company.com/team/app)
+		"image":   regexp.MustCompile(`^(?:/v[0-9.]+)?/images/(.+)/json$`),
+		"volume":  regexp.MustCompile(`^(?:/v[0-9.]+)?/volumes/([^/]+)$`),
+		"network": regexp.MustCompile(`^(?:/v[0-9.]+)?/networks/([^/]+)$`),
+	}
+
+	// Endpoints reading an object without returning its labels: the object is
+	// inspected before the request is forwarded
+	objectReadRoutes = []objectReadRoute{
+		{"container", http.MethodGet, regexp.MustCompile(`^(?:/v[0-9.]+)?/containers/([^/]+)/(?:logs|top|stats|changes|export|archive|attach/ws)$`)},
+		{"container", http.MethodPost, regexp.MustCompile(`^(?:/v[0-9.]+)?/containers/([^/]+)/attach$`)},
+		{"image", http.MethodGet, regexp.MustCompile(`^(?:/v[0-9.]+)?/images/(.+)/(?:history|get)$`)},
+	}
+
+	// docker save of several images names them in the query (names=...)
+	imagesExportPathPattern = regexp.MustCompile(`^(?:/v[0-9.]+)?/images/get$`)
+
+	eventsPathPattern = regexp.MustCompile(`^(?:/v[0-9.]+)?/events$`)
+)
+
+// objectReadRoute is an endpoint reading the object named in its path
+type objectReadRoute struct {
+	kind    string
+	method  string // HEAD is checked as GET
+	pattern *regexp.Regexp
+}
+
+// visibleObject holds the fields of a Docker object that decide its visibility.
+// Containers report Names and Config.Labels, images RepoTags and Labels (or
+// Config.Labels when inspected), volumes and networks Name and Labels.
+type visibleObject struct {
+	Name     string            `json:"Name"`
+	Names    []string          `json:"Names"`
+	RepoTags []string          `json:"RepoTags"`
+	Labels   map[string]string `json:"Labels"`
+	Config   *struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// names returns every name the object can be selected by
+func (o *visibleObject) names() []string {
+	names := append([]string{}, o.Names...)
+	names = append(names, o.RepoTags...)
+	if o.Name != "" {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+// labels returns the labels of the object
+func (o *visibleObject) labels() map[string]string {
+	if o.Labels == nil && o.Config != nil {
+		return o.Config.Labels
+	}
+	return o.Labels
+}
+
+// visibleTo reports whether the object is visible under every filter
+func (o *visibleObject) visibleTo(selected []*filters.AdvancedFilter) bool {
+	for _, filter := range selected {
+		if !filter.ObjectVisible(o.names(), o.labels()) {
+			return false
+		}
+	}
+	return true
+}
+
+// VisibilityMiddleware trims the list answers of the daemon (docker ps,
+// docker images, docker volume ls, docker network ls, docker system df) and
+// the event stream to the objects the caller may see, and answers 404 to the
+// inspection of other objects and to the reads of their logs, stats, output,
+// files, history or exports.
+// Filtered answers are buffered instead of streamed, except events.
+func VisibilityMiddleware(defaultFilter *filters.AdvancedFilter, inspector Inspector, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// An object must be visible under every selected policy
+		var selected []*filters.AdvancedFilter
+		for _, filter := range filtersFor(c, defaultFilter) {
+			if filter.VisibilityEnabled() {
+				selected = append(selected, filter)
+			}
+		}
+		if len(selected) == 0 {
+			c.Next()
+			return
+		}
+
+		path := c.Request.URL.Path
+		if kind, ids := objectReadTargets(c); kind != "" {
+			for _, id := range ids {
+				if !checkObjectVisible(c, selected, inspector, kind, id, logger) {
+					return
+				}
+			}
+			c.Next()
+			return
+		}
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		if eventsPathPattern.MatchString(path) {
+			filterEvents(c, selected)
+			return
+		}
+
+		list := listPathPattern.FindStringSubmatch(path)
+		kind, id := inspectTarget(path)
+		if list == nil && kind == "" {
+			c.Next()
+			return
+		}
+
+		// The answer is decoded, so it must not be compressed
+		c.Request.Header.Del("Accept-Encoding")
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if c.Writer.Status() != http.StatusOK {
+			writeBuffered(c, body)
+			return
+		}
+
+		if list != nil {
+			filtered, err := filterList(selected, list[1], body)
+			if err != nil {
+				logger.Errorf("Failed to filter %s: %v", path, err)
+				c.JSON(http.StatusBadGateway, gin.H{"message": "invalid answer from docker"})
+				return
+			}
+			writeBuffered(c, filtered)
+			return
+		}
+
+		var object visibleObject
+		if err := json.Unmarshal(body, &object); err != nil {
+			logger.Errorf("Failed to filter %s: %v", path, err)
+			c.JSON(http.StatusBadGateway, gin.H{"message": "invalid answer from docker"})
+			return
+		}
+		if !object.visibleTo(selected) {
+			logger.Debugf("Hiding %s %s from the caller", kind, id)
+			c.Writer.Header().Del("Content-Length")
+			c.JSON(http.StatusNotFound, gin.H{"message": "No such " + kind + ": " + id})
+			return
+		}
+		writeBuffered(c, body)
+	}
+}
+
+// inspectTarget returns the kind and ID of an inspected object, if any
+func inspectTarget(path string) (string, string) {
+	for kind, pattern := range inspectPathPatterns {
+		match := pattern.FindStringSubmatch(path)
+		if match != nil {
+			return kind, match[1]
+		}
+	}
+	return "", ""
+}
+
+// objectReadTargets returns the kind and IDs of the objects read without
+// inspection, if any
+func objectReadTargets(c *gin.Context) (string, []string) {
+	method := c.Request.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	path := c.Request.URL.Path
+	if method == http.MethodGet && imagesExportPathPattern.MatchString(path) {
+		return "image", c.QueryArray("names")
+	}
+	for _, route := range objectReadRoutes {
+		if route.method != method {
+			continue
+		}
+		if match := route.pattern.FindStringSubmatch(path); match != nil {
+			return route.kind, []string{match[1]}
+		}
+	}
+	return "", nil
+}
+
+// checkObjectVisible inspects an object and answers 404 when the caller may not see it
+func checkObjectVisible(c *gin.Context, selected []*filters.AdvancedFilter, inspector Inspector, kind, id string, logger *logrus.Logger) bool {
+	if inspector == nil {
+		logger.Errorf("Cannot check the visibility of %s %s: no inspector", kind, id)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"message": "failed to inspect " + kind + ": " + id})
+		return false
+	}
+
+	var object visibleObject
+	status, err := inspector.Inspect(c, "/"+kind+"s/"+id+"/json", &object)
+	switch {
+	case err != nil:
+		logger.Errorf("Failed to inspect %s %s: %v", kind, id, err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"message": "failed to inspect " + kind + ": " + id})
+	case status == http.StatusNotFound:
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "No such " + kind + ": " + id})
+	case status != http.StatusOK:
+		logger.Errorf("Failed to inspect %s %s: docker answered %d", kind, id, status)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"message": "failed to inspect " + kind + ": " + id})
+	case !object.visibleTo(selected):
+		logger.Debugf("Hiding %s %s from the caller", kind, id)
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"message": "No such " + kind + ": " + id})
+	default:
+		return true
+	}
+	return false
+}
+
+// filterList removes the objects the caller may not see from a list answer.
+// Items are copied unchanged; docker volume ls wraps them in {"Volumes": [...]},
+// docker system df lists images, containers and volumes in one object.
+func filterList(selected []*filters.AdvancedFilter, endpoint string, body []byte) ([]byte, error) {
+	keys, wrapped := listWrappers[endpoint]
+	if !wrapped {
+		return filterItems(selected, body)
+	}
+
+	var answer map[string]json.RawMessage
+	if err := json.Unmarshal(body, &answer); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		items, ok := answer[key]
+		if !ok || string(items) == "null" {
+			continue
+		}
+		filtered, err := filterItems(selected, items)
+		if err != nil {
+			return nil, err
+		}
+		answer[key] = filtered
+	}
+	return json.Marshal(answer)
+}
+
+// filterItems filters a JSON array of objects
+func filterItems(selected []*filters.AdvancedFilter, body []byte) ([]byte, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+
+	visible := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		var object visibleObject
+		if err := json.Unmarshal(item, &object); err != nil {
+			return nil, err
+		}
+		if object.visibleTo(selected) {
+			visible = append(visible, item)
+		}
+	}
+	return json.Marshal(visible)
+}
+
+// dockerEvent holds the fields of an event that decide its visibility.
+// Containers and images report their name and labels as actor attributes,
+// networks their name only, and volumes their name as actor ID.
+type dockerEvent struct {
+	Type  string `json:"Type"`
+	Actor struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// eventVisible reports whether an event line may be sent to the caller.
+// Events about other kinds of objects (daemon, plugins...) are kept.
+func eventVisible(selected []*filters.AdvancedFilter, line []byte) bool {
+	var event dockerEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return false
+	}
+
+	switch event.Type {
+	case "container", "image", "volume", "network":
+		attributes := event.Actor.Attributes
+		object := &visibleObject{Names: []string{event.Actor.ID}, Name: attributes["name"], Labels: attributes}
+		return object.visibleTo(selected)
+	default:
+		return true
+	}
+}
+
+// filterEvents streams the events the caller may see
+func filterEvents(c *gin.Context, selected []*filters.AdvancedFilter) {
+	// The stream is decoded, so it must not be compressed
+	c.Request.Header.Del("Accept-Encoding")
+
+	writer := &eventsWriter{ResponseWriter: c.Writer, selected: selected}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	// The last event may lack its trailing newline
+	if len(writer.pending) > 0 {
+		_ = writer.writeEvent(writer.pending)
+	}
+}
+
+// eventsWriter forwards the visible lines of the event stream as they arrive.
+// Error answers are forwarded unchanged.
+type eventsWriter struct {
+	gin.ResponseWriter
+	selected []*filters.AdvancedFilter
+	pending  []byte
+}
+
+func (w *eventsWriter) WriteHeader(code int) {
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *eventsWriter) Write(data []byte) (int, error) {
+	if w.Status() != http.StatusOK {
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.pending = append(w.pending, data...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+		line := w.pending[:end+1]
+		w.pending = w.pending[end+1:]
+		if err := w.writeEvent(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *eventsWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// writeEvent sends one event if the caller may see it
+func (w *eventsWriter) writeEvent(line []byte) error {
+	if len(bytes.TrimSpace(line)) == 0 || !eventVisible(w.selected, line) {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(line)
+	return err
+}
+
+// writeBuffered sends a buffered answer with its final length
+func writeBuffered(c *gin.Context, body []byte) {
+	c.Writer.Header().Del("Content-Length")
+	c.Writer.WriteHeaderNow()
+	_, _ = c.Writer.Write(body)
+}
+
+// bufferedWriter holds back the daemon answer until it is filtered.
+// The status code is recorded by the wrapped writer without being sent.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Flush does nothing: the answer is sent once complete
+func (w *bufferedWriter) Flush() {}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dockershield/pkg/filters"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeDaemon answers like the Docker daemon for the visibility tests
+func fakeDaemon(c *gin.Context) {
+	c.Header("Content-Type", "application/json")
+	switch c.Request.URL.Path {
+	case "/v1.43/containers/json":
+		c.String(http.StatusOK, `[{"Id":"1","Names":["/shop-web"],"Labels":{}},{"Id":"2","Names":["/db"],"Labels":{"project":"shop"}},{"Id":"3","Names":["/billing"],"Labels":{"project":"billing"}}]`)
+	case "/v1.43/images/json":
+		c.String(http.StatusOK, `[{"Id":"sha256:1","RepoTags":["registry.local/shop/web:1.0"],"Labels":null},{"Id":"sha256:2","RepoTags":["alpine:3"],"Labels":null}]`)
+	case "/v1.43/volumes":
+		c.String(http.StatusOK, `{"Volumes":[{"Name":"shop-data","Labels":null},{"Name":"billing-data","Labels":null}],"Warnings":[]}`)
+	case "/v1.43/networks":
+		c.String(http.StatusOK, `[{"Name":"bridge","Labels":{}},{"Name":"shop-net","Labels":{}}]`)
+	case "/v1.43/system/df":
+		c.String(http.StatusOK, `{"LayersSize":10,"Images":[{"Id":"sha256:1","RepoTags":["registry.local/shop/web:1.0"],"Labels":{}},{"Id":"sha256:2","RepoTags":["alpine:3"],"Labels":{}}],"Containers":[{"Id":"2","Names":["/db"],"Labels":{"project":"shop"}},{"Id":"3","Names":["/billing"],"Labels":{"project":"billing"}}],"Volumes":[{"Name":"shop-data","Labels":null},{"Name":"billing-data","Labels":null}],"BuildCache":null}`)
+	case "/v1.43/containers/db/json":
+		c.String(http.StatusOK, `{"Id":"2","Name":"/db","Config":{"Labels":{"project":"shop"}}}`)
+	case "/v1.43/containers/billing/json":
+		c.String(http.StatusOK, `{"Id":"3","Name":"/billing","Config":{"Labels":{"project":"billing"}}}`)
+	case "/v1.43/images/registry.local/shop/web:1.0/json":
+		c.String(http.StatusOK, `{"Id":"sha256:1","RepoTags":["registry.local/shop/web:1.0"],"Config":{"Labels":null}}`)
+	case "/v1.43/volumes/billing-data":
+		c.String(http.StatusOK, `{"Name":"billing-data","Labels":null}`)
+	case "/v1.43/events":
+		// Events are flushed one by one, the last one split across writes
+		for _, chunk := range []string{
+			`{"Type":"container","Action":"start","Actor":{"ID":"2","Attributes":{"name":"db","project":"shop"}}}` + "\n",
+			`{"Type":"container","Action":"start","Actor":{"ID":"3","Attributes":{"name":"billing","project":"billing"}}}` + "\n",
+			`{"Type":"volume","Action":"create","Actor":{"ID":"shop-data","Attributes":{"driver":"local"}}}` + "\n",
+			`{"Type":"daemon","Action":"reload","Actor":{"ID":"x","Attributes":{}}}` + "\n",
+			`{"Type":"network","Action":"create",`, `"Actor":{"ID":"n1","Attributes":{"name":"shop-net"}}}` + "\n",
+		} {
+			_, _ = c.Writer.Write([]byte(chunk))
+			c.Writer.Flush()
+		}
+	default:
+		if strings.HasPrefix(c.Request.URL.Path, "/v1.43/networks/") {
+			c.String(http.StatusNotFound, `{"message":"not found"}`)
+			return
+		}
+		c.String(http.StatusOK, `"data"`)
+	}
+}
+
+func TestVisibilityMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	filter := &filters.AdvancedFilter{
+		Visibility: &filters.VisibilityFilter{
+			Labels: map[string]string{"project": "^shop$"},
+			Names:  []string{"^shop-", "^registry\\.local/shop/"},
+		},
+	}
+	inspector := &fakeInspector{objects: map[string]string{
+		"/containers/db/json":                      `{"Id":"2","Name":"/db","Config":{"Labels":{"project":"shop"}}}`,
+		"/containers/billing/json":                 `{"Id":"3","Name":"/billing","Config":{"Labels":{"project":"billing"}}}`,
+		"/images/registry.local/shop/web:1.0/json": `{"Id":"sha256:1","RepoTags":["registry.local/shop/web:1.0"],"Config":{"Labels":null}}`,
+		"/images/alpine:3/json":                    `{"Id":"sha256:2","RepoTags":["alpine:3"],"Config":{"Labels":null}}`,
+	}}
+
+	router := gin.New()
+	router.Use(VisibilityMiddleware(filter, inspector, logrus.New()))
+	router.Any("/*path", fakeDaemon)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Container list", "GET", "/v1.43/containers/json", http.StatusOK, `[{"Id":"1","Names":["/shop-web"],"Labels":{}},{"Id":"2","Names":["/db"],"Labels":{"project":"shop"}}]`},
+		{"Image list", "GET", "/v1.43/images/json", http.StatusOK, `[{"Id":"sha256:1","RepoTags":["registry.local/shop/web:1.0"],"Labels":null}]`},
+		{"Volume list", "GET", "/v1.43/volumes", http.StatusOK, `{"Volumes":[{"Name":"shop-data","Labels":null}],"Warnings":[]}`},
+		{"Network list", "GET", "/v1.43/networks", http.StatusOK, `[{"Name":"shop-net","Labels":{}}]`},
+		{"Disk usage", "GET", "/v1.43/system/df", http.StatusOK, `{"BuildCache":null,"Containers":[{"Id":"2","Names":["/db"],"Labels":{"project":"shop"}}],"Images":[{"Id":"sha256:1","RepoTags":["registry.local/shop/web:1.0"],"Labels":{}}],"LayersSize":10,"Volumes":[{"Name":"shop-data","Labels":null}]}`},
+		{"Visible container", "GET", "/v1.43/containers/db/json", http.StatusOK, `{"Id":"2","Name":"/db","Config":{"Labels":{"project":"shop"}}}`},
+		{"Hidden container", "GET", "/v1.43/containers/billing/json", http.StatusNotFound, `{"message":"No such container: billing"}`},
+		{"Visible image", "GET", "/v1.43/images/registry.local/shop/web:1.0/json", http.StatusOK, `{"Id":"sha256:1","RepoTags":["registry.local/shop/web:1.0"],"Config":{"Labels":null}}`},
+		{"Hidden volume", "GET", "/v1.43/volumes/billing-data", http.StatusNotFound, `{"message":"No such volume: billing-data"}`},
+		{"Missing network", "GET", "/v1.43/networks/unknown", http.StatusNotFound, `{"message":"not found"}`},
+		{"Logs of visible container", "GET", "/v1.43/containers/db/logs", http.StatusOK, `"data"`},
+		{"Logs of hidden container", "GET", "/v1.43/containers/billing/logs", http.StatusNotFound, `{"message":"No such container: billing"}`},
+		{"Stats of hidden container", "GET", "/v1.43/containers/billing/stats", http.StatusNotFound, `{"message":"No such container: billing"}`},
+		{"Logs of missing container", "GET", "/v1.43/containers/gone/logs", http.StatusNotFound, `{"message":"No such container: gone"}`},
+		{"Attach to visible container", "POST", "/v1.43/containers/db/attach?logs=1&stream=0&stdout=1", http.StatusOK, `"data"`},
+		{"Attach to hidden container", "POST", "/v1.43/containers/billing/attach?logs=1&stream=0&stdout=1", http.StatusNotFound, `{"message":"No such container: billing"}`},
+		{"Websocket attach to hidden container", "GET", "/v1.43/containers/billing/attach/ws?logs=1", http.StatusNotFound, `{"message":"No such container: billing"}`},
+		{"History of hidden image", "GET", "/v1.43/images/alpine:3/history", http.StatusNotFound, `{"message":"No such image: alpine:3"}`},
+		{"Export of visible image", "GET", "/v1.43/images/registry.local/shop/web:1.0/get", http.StatusOK, `"data"`},
+		{"Export of hidden image", "GET", "/v1.43/images/alpine:3/get", http.StatusNotFound, `{"message":"No such image: alpine:3"}`},
+		{"Save of visible images", "GET", "/v1.43/images/get?names=registry.local/shop/web:1.0", http.StatusOK, `"data"`},
+		{"Save including hidden image", "GET", "/v1.43/images/get?names=registry.local/shop/web:1.0&names=alpine:3", http.StatusNotFound, `{"message":"No such image: alpine:3"}`},
+		{"Events", "GET", "/v1.43/events", http.StatusOK, `{"Type":"container","Action":"start","Actor":{"ID":"2","Attributes":{"name":"db","project":"shop"}}}
+{"Type":"volume","Action":"create","Actor":{"ID":"shop-data","Attributes":{"driver":"local"}}}
+{"Type":"daemon","Action":"reload","Actor":{"ID":"x","Attributes":{}}}
+{"Type":"network","Action":"create","Actor":{"ID":"n1","Attributes":{"name":"shop-net"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"dockershield/config"
+	"dockershield/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// backend is a Docker daemon reachable by the proxy
+type backend struct {
+	target    *url.URL
+	transport *http.Transport
+	proxy     *httputil.ReverseProxy
+}
+
+// newBackend creates the streaming reverse proxy for a Docker daemon
+func (h *Handler) newBackend(dockerSocket string, dockerTLS *config.DockerTLS) (*backend, error) {
+	target, transport, err := newUpstreamTransport(dockerSocket, dockerTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backend{
+		target:    target,
+		transport: transport,
+		proxy: &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(target)
+			},
+			Transport: transport,
+			// Flush every write so that logs -f, events, stats and pull
+			// progress reach the client as soon as the daemon emits them
+			FlushInterval: -1,
+			ErrorHandler:  h.handleError,
+		},
+	}, nil
+}
+
+// newUpstreamTransport returns the base URL and transport used to reach the Docker daemon.
+// TLS settings only apply to tcp:// daemons.
+func newUpstreamTransport(dockerSocket string, dockerTLS *config.DockerTLS) (*url.URL, *http.Transport, error) {
+	transport := &http.Transport{
+		DisableCompression: true,
+	}
+
+	// Configure for Unix socket or TCP connection
+	if strings.HasPrefix(dockerSocket, "tcp://") {
+		target := &url.URL{Scheme: "http", Host: strings.TrimPrefix(dockerSocket, "tcp://")}
+		if dockerTLS != nil {
+			tlsConfig, err := dockerTLS.ClientConfig()
+			if err != nil {
+				return nil, nil, err
+			}
+			transport.TLSClientConfig = tlsConfig
+			target.Scheme = "https"
+		}
+		return target, transport, nil
+	}
+
+	// Default to unix socket
+	socketPath := strings.TrimPrefix(dockerSocket, "unix://")
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socketPath)
+	}
+	return &url.URL{Scheme: "http", Host: "unix"}, transport, nil
+}
+
+// dial opens a raw connection to the Docker daemon
+func (b *backend) dial(ctx context.Context) (net.Conn, error) {
+	if b.transport.DialContext != nil {
+		return b.transport.DialContext(ctx, "tcp", b.target.Host)
+	}
+	if b.transport.TLSClientConfig != nil {
+		dialer := &tls.Dialer{Config: b.transport.TLSClientConfig}
+		return dialer.DialContext(ctx, "tcp", b.target.Host)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", b.target.Host)
+}
+
+// backendFor returns the daemon selected for the request by UpstreamMiddleware.
+// An upstream the handler was not built with is an error, never the default daemon.
+func (h *Handler) backendFor(c *gin.Context) (*backend, error) {
+	name := c.GetString(middleware.UpstreamKey)
+	if name == "" {
+		return h.backend, nil
+	}
+	b, ok := h.upstreams[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown upstream %s", name)
+	}
+	return b, nil
+}
@@ -2,158 +2,120 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net"
 	"net/http"
-	"strings"
-	"time"
 
 	"dockershield/config"
+	"dockershield/internal/middleware"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-resty/resty/v2"
 )
 
 // Handler handles Docker API proxy requests
 type Handler struct {
-	client       *resty.Client
+	backend      *backend            // Daemon behind DOCKER_SOCKET
+	upstreams    map[string]*backend // Additional daemons by upstream name
 	dockerSocket string
 	config       *config.Config
 }
 
 // NewHandler creates a new proxy handler
-func NewHandler(cfg *config.Config) *Handler {
-	client := resty.New()
-
-	// Configure for Unix socket or TCP connection
-	if strings.HasPrefix(cfg.DockerSocket, "unix://") {
-		socketPath := strings.TrimPrefix(cfg.DockerSocket, "unix://")
-		client.SetTransport(&http.Transport{
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return net.Dial("unix", socketPath)
-			},
-		})
-		client.SetBaseURL("http://unix")
-	} else if strings.HasPrefix(cfg.DockerSocket, "tcp://") {
-		client.SetBaseURL(cfg.DockerSocket)
-	} else {
-		// Default to unix socket
-		client.SetTransport(&http.Transport{
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return net.Dial("unix", cfg.DockerSocket)
-			},
-		})
-		client.SetBaseURL("http://unix")
-	}
-
-	client.SetTimeout(60 * time.Second)
-	client.SetRetryCount(0)
-
-	return &Handler{
-		client:       client,
+func NewHandler(cfg *config.Config) (*Handler, error) {
+	h := &Handler{
+		upstreams:    make(map[string]*backend, len(cfg.Upstreams)),
 		dockerSocket: cfg.DockerSocket,
 		config:       cfg,
 	}
+
+	var err error
+	if h.backend, err = h.newBackend(cfg.DockerSocket, cfg.DockerTLS); err != nil {
+		return nil, fmt.Errorf("docker socket %s: %w", cfg.DockerSocket, err)
+	}
+	for _, upstream := range cfg.Upstreams {
+		if h.upstreams[upstream.Name], err = h.newBackend(upstream.DockerSocket, upstream.DockerTLS); err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", upstream.Name, err)
+		}
+	}
+
+	return h, nil
 }
 
 // ProxyRequest proxies the request to Docker socket
 func (h *Handler) ProxyRequest(c *gin.Context) {
-	targetPath := buildTargetPath(c)
-	req := h.prepareRequest(c)
-	if req == nil {
-		return // Error already handled
+	if !isProxiedMethod(c.Request.Method) {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{
+			"error": fmt.Sprintf("method %s not allowed", c.Request.Method),
+		})
+		return
 	}
 
-	resp, err := h.executeRequest(req, targetPath, c)
-	if err != nil {
-		return // Error already handled
+	// attach, exec start, session and grpc switch to a raw bidirectional stream
+	if isUpgradeRequest(c.Request) {
+		h.proxyHijacked(c)
+		return
 	}
 
-	h.sendResponse(c, resp)
-}
-
-// buildTargetPath constructs the target path with query parameters
-func buildTargetPath(c *gin.Context) string {
-	targetPath := c.Request.URL.Path
-	if c.Request.URL.RawQuery != "" {
-		targetPath += "?" + c.Request.URL.RawQuery
+	// Request and response bodies are streamed, never buffered.
+	// The upstream request carries the client context: a disconnect, a
+	// deadline or an idle timeout aborts it and releases the daemon connection.
+	backend, err := h.backendFor(c)
+	if err != nil {
+		h.handleError(c.Writer, c.Request, err)
+		return
 	}
-	return targetPath
+	defer h.recoverAbort(c)
+	backend.proxy.ServeHTTP(c.Writer, c.Request)
 }
 
-// prepareRequest creates and configures a resty request
-func (h *Handler) prepareRequest(c *gin.Context) *resty.Request {
-	req := h.client.R()
-
-	// Copy headers
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			req.SetHeader(key, value)
-		}
+// recoverAbort handles a stream interrupted after the response has started
+// (client gone, deadline or idle timeout). The client connection is closed so
+// that a truncated download is not mistaken for a complete one.
+func (h *Handler) recoverAbort(c *gin.Context) {
+	err := recover()
+	if err == nil {
+		return
 	}
-
-	// Copy body if present
-	if c.Request.Body != nil {
-		body, err := io.ReadAll(c.Request.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "failed to read request body",
-			})
-			return nil
-		}
-		req.SetBody(body)
+	if err != http.ErrAbortHandler {
+		panic(err)
 	}
 
-	return req
+	if conn, _, hijackErr := http.NewResponseController(c.Writer).Hijack(); hijackErr == nil {
+		conn.Close()
+	}
+	c.Abort()
 }
 
-// executeRequest executes the HTTP request using the appropriate method
-func (h *Handler) executeRequest(req *resty.Request, targetPath string, c *gin.Context) (*resty.Response, error) {
-	var resp *resty.Response
-	var err error
-
-	switch c.Request.Method {
-	case http.MethodGet:
-		resp, err = req.Get(targetPath)
-	case http.MethodPost:
-		resp, err = req.Post(targetPath)
-	case http.MethodPut:
-		resp, err = req.Put(targetPath)
-	case http.MethodDelete:
-		resp, err = req.Delete(targetPath)
-	case http.MethodPatch:
-		resp, err = req.Patch(targetPath)
-	case http.MethodHead:
-		resp, err = req.Head(targetPath)
-	case http.MethodOptions:
-		resp, err = req.Options(targetPath)
+// isProxiedMethod reports whether the HTTP method can be forwarded to Docker
+func isProxiedMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete,
+		http.MethodPatch, http.MethodHead, http.MethodOptions:
+		return true
 	default:
-		c.JSON(http.StatusMethodNotAllowed, gin.H{
-			"error": fmt.Sprintf("method %s not allowed", c.Request.Method),
-		})
-		return nil, fmt.Errorf("method not allowed")
-	}
-
-	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{
-			"error": fmt.Sprintf("failed to proxy request: %v", err),
-		})
-		return nil, err
+		return false
 	}
-
-	return resp, nil
 }
 
-// sendResponse sends the proxied response back to the client
-func (h *Handler) sendResponse(c *gin.Context, resp *resty.Response) {
-	// Copy response headers
-	for key, values := range resp.Header() {
-		for _, value := range values {
-			c.Header(key, value)
-		}
+// handleError reports upstream failures to the client
+func (h *Handler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadGateway
+	message := fmt.Sprintf("failed to proxy request: %v", err)
+
+	switch cause := context.Cause(r.Context()); {
+	case errors.Is(cause, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+		message = fmt.Sprintf("request to Docker timed out: %v", cause)
+	case cause != nil:
+		// The client went away: nobody is left to read an error body
+		w.WriteHeader(middleware.StatusClientClosedRequest)
+		return
 	}
 
-	// Send response
-	c.Data(resp.StatusCode(), resp.Header().Get("Content-Type"), resp.Body())
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(gin.H{
+		"error": message,
+	})
 }
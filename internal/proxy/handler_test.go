@@ -0,0 +1,320 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dockershield/config"
+	"dockershield/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newUnixUpstream starts a fake Docker daemon listening on a Unix socket
+func newUnixUpstream(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on unix socket: %v", err)
+	}
+
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = srv.Serve(listener) }()
+	t.Cleanup(func() { srv.Close() })
+
+	return "unix://" + socketPath
+}
+
+// newTestProxy starts the proxy handler in front of the given Docker socket
+func newTestProxy(t *testing.T, dockerSocket string) *httptest.Server {
+	t.Helper()
+
+	return newTestServer(t, mustNewHandler(t, &config.Config{DockerSocket: dockerSocket}))
+}
+
+// newTestServer serves the proxy handler without any middleware
+func newTestServer(t *testing.T, handler *Handler) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Any("/*path", handler.ProxyRequest)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// readAll returns the response body as a string
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return string(body)
+}
+
+// mustNewHandler creates the proxy handler or fails the test
+func mustNewHandler(t *testing.T, cfg *config.Config) *Handler {
+	t.Helper()
+
+	handler, err := NewHandler(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	return handler
+}
+
+func TestProxyRequestForwardsRequest(t *testing.T) {
+	socket := newUnixUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Api-Version", "1.41")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.RequestURI() + " " + string(body)))
+	}))
+	srv := newTestProxy(t, socket)
+
+	resp, err := http.Post(srv.URL+"/v1.41/containers/create?name=web", "application/json", strings.NewReader(`{"Image":"nginx"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Api-Version") != "1.41" {
+		t.Errorf("Expected Api-Version header to be forwarded, got '%s'", resp.Header.Get("Api-Version"))
+	}
+	expected := `POST /v1.41/containers/create?name=web {"Image":"nginx"}`
+	if string(body) != expected {
+		t.Errorf("Expected body '%s', got '%s'", expected, string(body))
+	}
+}
+
+func TestProxyRequestStreamsResponse(t *testing.T) {
+	release := make(chan struct{})
+	socket := newUnixUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{\"status\":\"start\"}\n"))
+		w.(http.Flusher).Flush()
+
+		// Keep the stream open until the client has seen the first event
+		<-release
+		_, _ = w.Write([]byte("{\"status\":\"die\"}\n"))
+	}))
+	srv := newTestProxy(t, socket)
+
+	resp, err := http.Get(srv.URL + "/v1.41/events")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := reader.ReadString('\n')
+		lines <- line
+	}()
+
+	select {
+	case line := <-lines:
+		if line != "{\"status\":\"start\"}\n" {
+			t.Errorf("Unexpected first event: %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("First event was not streamed before the upstream response completed")
+	}
+
+	close(release)
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "{\"status\":\"die\"}\n" {
+		t.Errorf("Unexpected remaining events: %q", string(rest))
+	}
+}
+
+func TestProxyRequestUpstreamUnavailable(t *testing.T) {
+	srv := newTestProxy(t, "unix://"+filepath.Join(t.TempDir(), "missing.sock"))
+
+	resp, err := http.Get(srv.URL + "/v1.41/containers/json")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("Expected JSON error response, got '%s'", resp.Header.Get("Content-Type"))
+	}
+}
+
+func TestProxyRequestMethodNotAllowed(t *testing.T) {
+	srv := newTestProxy(t, "unix:///nonexistent.sock")
+
+	req, _ := http.NewRequest("TRACE", srv.URL+"/v1.41/containers/json", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestProxyRequestPropagatesClientCancellation(t *testing.T) {
+	upstreamCancelled := make(chan struct{})
+	socket := newUnixUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Emulate a long "wait" or pull that only ends when the caller goes away
+		<-r.Context().Done()
+		close(upstreamCancelled)
+	}))
+	srv := newTestProxy(t, socket)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "POST", srv.URL+"/v1.41/containers/abc/wait", nil)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+
+	select {
+	case <-upstreamCancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Client cancellation was not propagated to the Docker daemon")
+	}
+}
+
+func TestProxyRequestDeadlineExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstreamCancelled := make(chan struct{})
+	socket := newUnixUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(upstreamCancelled)
+	}))
+
+	cfg := &config.Config{
+		DockerSocket: socket,
+		Timeouts:     &config.Timeouts{Short: 100 * time.Millisecond, Default: 100 * time.Millisecond},
+	}
+	router := gin.New()
+	router.Use(middleware.TimeoutMiddleware(cfg.Timeouts))
+	router.Any("/*path", mustNewHandler(t, cfg).ProxyRequest)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1.41/containers/json")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", resp.StatusCode)
+	}
+
+	select {
+	case <-upstreamCancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Deadline was not propagated to the Docker daemon")
+	}
+}
+
+func TestProxyRequestRoutesToUpstream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	daemon := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name + " " + r.URL.Path))
+		})
+	}
+
+	cfg := &config.Config{
+		DockerSocket: newUnixUpstream(t, daemon("rootful")),
+		Upstreams: []*config.Upstream{
+			{
+				Name:         "rootless",
+				DockerSocket: newUnixUpstream(t, daemon("rootless")),
+				PathPrefix:   "/rootless",
+				AccessRules:  &config.AccessRules{},
+			},
+		},
+	}
+	router := gin.New()
+	router.Use(middleware.UpstreamMiddleware(cfg.Upstreams, nil, nil))
+	router.Any("/*path", mustNewHandler(t, cfg).ProxyRequest)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/rootless/v1.41/containers/json", "rootless /v1.41/containers/json"},
+		{"/v1.41/containers/json", "rootful /v1.41/containers/json"},
+	}
+
+	for _, tt := range tests {
+		resp, err := http.Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != tt.expected {
+			t.Errorf("For path %s: expected '%s', got '%s'", tt.path, tt.expected, string(body))
+		}
+	}
+}
+
+func TestProxyRequestUnknownUpstream(t *testing.T) {
+	called := false
+	daemon := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.UpstreamKey, "missing")
+	})
+	router.Any("/*path", mustNewHandler(t, &config.Config{DockerSocket: newUnixUpstream(t, daemon)}).ProxyRequest)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1.41/containers/json")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", resp.StatusCode)
+	}
+	if called {
+		t.Error("Expected the request not to reach the default daemon")
+	}
+}
@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// closeWriter is implemented by connections supporting half-close (TCP, Unix)
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// isUpgradeRequest reports whether the client asks Docker to hijack the connection.
+// The Docker CLI sends "Connection: Upgrade" with "Upgrade: tcp" for attach and
+// exec start, and "Upgrade: h2c" for BuildKit sessions.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRawStream reports whether Docker answered with a hijacked stream without
+// switching protocols, as older API clients expect
+func isRawStream(resp *http.Response) bool {
+	contentType := resp.Header.Get("Content-Type")
+	return resp.StatusCode == http.StatusOK &&
+		(contentType == "application/vnd.docker.raw-stream" ||
+			contentType == "application/vnd.docker.multiplexed-stream")
+}
+
+// proxyHijacked relays an upgraded connection between the client and Docker.
+// ACL and advanced filters have already run when this handler is reached.
+func (h *Handler) proxyHijacked(c *gin.Context) {
+	backend, err := h.backendFor(c)
+	if err != nil {
+		h.handleError(c.Writer, c.Request, err)
+		return
+	}
+	upstream, err := backend.dial(c.Request.Context())
+	if err != nil {
+		h.handleError(c.Writer, c.Request, err)
+		return
+	}
+	defer upstream.Close()
+
+	// The raw connection ignores the request context: close it when the
+	// client goes away or the deadline expires during the upgrade handshake
+	stopWatch := context.AfterFunc(c.Request.Context(), func() {
+		upstream.Close()
+	})
+	defer stopWatch()
+
+	outReq := c.Request.Clone(c.Request.Context())
+	outReq.URL.Scheme = backend.target.Scheme
+	outReq.URL.Host = backend.target.Host
+	outReq.Host = backend.target.Host
+	outReq.RequestURI = ""
+
+	if err := outReq.Write(upstream); err != nil {
+		h.handleError(c.Writer, c.Request, err)
+		return
+	}
+
+	upstreamReader := bufio.NewReader(upstream)
+	resp, err := http.ReadResponse(upstreamReader, outReq)
+	if err != nil {
+		h.handleError(c.Writer, c.Request, err)
+		return
+	}
+
+	// Docker refused the upgrade (unknown container, bad request...):
+	// relay its answer as a regular response
+	if resp.StatusCode != http.StatusSwitchingProtocols && !isRawStream(resp) {
+		defer resp.Body.Close()
+		for key, values := range resp.Header {
+			for _, value := range values {
+				c.Writer.Header().Add(key, value)
+			}
+		}
+		c.Status(resp.StatusCode)
+		_, _ = io.Copy(c.Writer, resp.Body)
+		return
+	}
+
+	// Once hijacked, the request context no longer tracks the client: the
+	// relay ends when either side closes its connection or stays idle
+	if !stopWatch() {
+		return
+	}
+
+	// Record the status for the logging middleware before taking the connection over
+	c.Writer.WriteHeader(resp.StatusCode)
+	client, clientBuf, err := http.NewResponseController(c.Writer).Hijack()
+	if err != nil {
+		h.handleError(c.Writer, c.Request, err)
+		return
+	}
+	defer client.Close()
+
+	// Server read/write timeouts do not apply to hijacked streams
+	_ = client.SetDeadline(time.Time{})
+
+	if err := writeResponseHead(clientBuf.Writer, resp); err != nil {
+		return
+	}
+
+	relay(client, clientBuf.Reader, upstream, upstreamReader, h.streamIdleTimeout())
+}
+
+// streamIdleTimeout returns the maximum idle time of a hijacked stream (0 = disabled)
+func (h *Handler) streamIdleTimeout() time.Duration {
+	if h.config == nil || h.config.Timeouts == nil {
+		return 0
+	}
+	return h.config.Timeouts.StreamIdle
+}
+
+// writeResponseHead sends the status line and headers of the upgrade response
+func writeResponseHead(w *bufio.Writer, resp *http.Response) error {
+	if _, err := fmt.Fprintf(w, "HTTP/1.1 %s\r\n", resp.Status); err != nil {
+		return err
+	}
+	if err := resp.Header.Write(w); err != nil {
+		return err
+	}
+	if _, err := w.WriteString("\r\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// relay copies data in both directions until Docker ends the stream.
+// When the client closes its write side (end of stdin), the close is
+// propagated upstream so the container still delivers its remaining output.
+// Both connections are closed once no data flowed for idle (if non-zero).
+func relay(client net.Conn, clientReader io.Reader, upstream net.Conn, upstreamReader io.Reader, idle time.Duration) {
+	done := make(chan struct{})
+
+	if idle > 0 {
+		timer := time.AfterFunc(idle, func() {
+			client.Close()
+			upstream.Close()
+		})
+		defer timer.Stop()
+
+		clientReader = &activityReader{Reader: clientReader, timer: timer, idle: idle}
+		upstreamReader = &activityReader{Reader: upstreamReader, timer: timer, idle: idle}
+	}
+
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(client, upstreamReader)
+		if cw, ok := client.(closeWriter); ok {
+			_ = cw.CloseWrite()
+		}
+	}()
+
+	go func() {
+		_, _ = io.Copy(upstream, clientReader)
+		if cw, ok := upstream.(closeWriter); ok {
+			_ = cw.CloseWrite()
+		}
+	}()
+
+	<-done
+}
+
+// activityReader postpones the idle timer of a hijacked stream on each read
+type activityReader struct {
+	io.Reader
+	timer *time.Timer
+	idle  time.Duration
+}
+
+func (r *activityReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.idle)
+	}
+	return n, err
+}
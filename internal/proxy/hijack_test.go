@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// attachUpstream emulates Docker's attach endpoint: it echoes stdin until the
+// client half-closes, then writes a trailer and ends the stream
+func attachUpstream(t *testing.T) string {
+	return newUnixUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/attach") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"No such container: missing"}`))
+			return
+		}
+
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Upstream hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = buf.WriteString("HTTP/1.1 101 UPGRADED\r\n" +
+			"Content-Type: application/vnd.docker.raw-stream\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Upgrade: tcp\r\n\r\n")
+		_ = buf.Flush()
+
+		stdin, _ := io.ReadAll(buf)
+		_, _ = conn.Write([]byte("echo:" + string(stdin) + ":bye"))
+	}))
+}
+
+// dialUpgrade sends an upgrade request through the proxy and returns the raw connection
+func dialUpgrade(t *testing.T, proxyURL, path string) (*net.TCPConn, *bufio.Reader, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxyURL, "http://"))
+	if err != nil {
+		t.Fatalf("Failed to dial proxy: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: docker\r\nConnection: Upgrade\r\nUpgrade: tcp\r\nContent-Length: 0\r\n\r\n", path)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Failed to read upgrade response: %v", err)
+	}
+	return conn.(*net.TCPConn), reader, resp
+}
+
+func TestIsUpgradeRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection string
+		upgrade    string
+		expected   bool
+	}{
+		{"Docker attach", "Upgrade", "tcp", true},
+		{"BuildKit session", "keep-alive, Upgrade", "h2c", true},
+		{"Lowercase token", "upgrade", "tcp", true},
+		{"Missing Upgrade header", "Upgrade", "", false},
+		{"Missing Connection token", "keep-alive", "tcp", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/v1.41/containers/abc/attach", nil)
+			if tt.connection != "" {
+				req.Header.Set("Connection", tt.connection)
+			}
+			if tt.upgrade != "" {
+				req.Header.Set("Upgrade", tt.upgrade)
+			}
+			if result := isUpgradeRequest(req); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestProxyHijackedRelaysBothDirections(t *testing.T) {
+	srv := newTestProxy(t, attachUpstream(t))
+
+	conn, reader, resp := dialUpgrade(t, srv.URL, "/v1.41/containers/abc/attach?stream=1&stdin=1")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status 101, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Content-Type") != "application/vnd.docker.raw-stream" {
+		t.Errorf("Expected raw-stream content type, got '%s'", resp.Header.Get("Content-Type"))
+	}
+
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatalf("Failed to write stdin: %v", err)
+	}
+	// Closing stdin must not cut the output still coming from the container
+	if err := conn.CloseWrite(); err != nil {
+		t.Fatalf("Failed to half-close: %v", err)
+	}
+
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(output) != "echo:hello:bye" {
+		t.Errorf("Expected 'echo:hello:bye', got '%s'", string(output))
+	}
+}
+
+func TestProxyHijackedRelaysRefusedUpgrade(t *testing.T) {
+	srv := newTestProxy(t, attachUpstream(t))
+
+	_, _, resp := dialUpgrade(t, srv.URL, "/v1.41/exec/missing/start")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "No such container") {
+		t.Errorf("Expected upstream error body, got '%s'", string(body))
+	}
+}
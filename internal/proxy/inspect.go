@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxInspectBody caps the daemon answers decoded by Inspect
+const maxInspectBody = 16 << 20
+
+// Inspect sends a GET request for path to the daemon selected for the request
+// and decodes its JSON answer into v. It returns the daemon status code; v is
+// only filled on 200.
+func (h *Handler) Inspect(c *gin.Context, path string, v interface{}) (int, error) {
+	b, err := h.backendFor(c)
+	if err != nil {
+		return 0, err
+	}
+	target := *b.target
+	target.Path = path
+	target.RawQuery = ""
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, target.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := b.transport.RoundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxInspectBody))
+		return resp.StatusCode, nil
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxInspectBody)).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid answer from docker for %s: %w", path, err)
+	}
+	return resp.StatusCode, nil
+}
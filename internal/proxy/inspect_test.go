@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dockershield/config"
+	"dockershield/internal/middleware"
+	"dockershield/pkg/filters"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestContainerCreateInspectsImageUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	created := 0
+	daemon := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/images/registry.local/app:1.0/json":
+			_, _ = w.Write([]byte(`{"Id":"sha256:1","Config":{"User":"1000:1000"}}`))
+		case "/images/alpine/json":
+			_, _ = w.Write([]byte(`{"Id":"sha256:2","Config":{"User":""}}`))
+		case "/images/broken/json":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/v1.43/containers/create":
+			created++
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"Id":"abc"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"No such image"}`))
+		}
+	})
+
+	filter := &filters.AdvancedFilter{
+		Containers: &filters.ContainerFilter{
+			User: &filters.UserFilter{DenyRoot: true, InspectImage: true},
+		},
+	}
+	handler := mustNewHandler(t, &config.Config{DockerSocket: newUnixUpstream(t, daemon)})
+
+	router := gin.New()
+	router.Use(middleware.AdvancedFilterMiddleware(filter, handler, logrus.New()))
+	router.Any("/*path", handler.ProxyRequest)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectCreated  bool
+	}{
+		{"Image with a user", `{"Image":"registry.local/app:1.0"}`, http.StatusCreated, true},
+		{"Image running as root", `{"Image":"alpine"}`, http.StatusForbidden, false},
+		{"User set in the request", `{"Image":"alpine","User":"1000"}`, http.StatusCreated, true},
+		{"Root set in the request", `{"Image":"registry.local/app:1.0","User":"root"}`, http.StatusForbidden, false},
+		{"Missing image", `{"Image":"unknown"}`, http.StatusNotFound, false},
+		{"Daemon error", `{"Image":"broken"}`, http.StatusBadGateway, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := created
+			resp, err := http.Post(srv.URL+"/v1.43/containers/create", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			body := readAll(t, resp)
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, resp.StatusCode, body)
+			}
+			if (created > before) != tt.expectCreated {
+				t.Errorf("Expected container created=%v", tt.expectCreated)
+			}
+		})
+	}
+}
+
+func TestExecCreateInspectsTargetContainer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	created := 0
+	daemon := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/db/json":
+			_, _ = w.Write([]byte(`{"Id":"1","Name":"/postgres-main","Config":{"Image":"postgres:16","Labels":{}}}`))
+		case "/containers/web/json":
+			_, _ = w.Write([]byte(`{"Id":"2","Name":"/web","Config":{"Image":"nginx","Labels":{"team":"web"}}}`))
+		case "/v1.43/containers/db/exec", "/v1.43/containers/web/exec":
+			created++
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"Id":"exec1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"No such container"}`))
+		}
+	})
+
+	filter := &filters.AdvancedFilter{
+		Exec: &filters.ExecFilter{
+			Rules: []filters.ExecRule{
+				{Containers: []string{"^postgres-"}, AllowedCommands: []string{"^pg_dump( |$)"}},
+			},
+		},
+	}
+	handler := mustNewHandler(t, &config.Config{DockerSocket: newUnixUpstream(t, daemon)})
+
+	router := gin.New()
+	router.Use(middleware.AdvancedFilterMiddleware(filter, handler, logrus.New()))
+	router.Any("/*path", handler.ProxyRequest)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	tests := []struct {
+		name           string
+		container      string
+		body           string
+		expectedStatus int
+	}{
+		{"Allowed command", "db", `{"Cmd":["pg_dump","app"]}`, http.StatusCreated},
+		{"Other command", "db", `{"Cmd":["psql"]}`, http.StatusForbidden},
+		{"Container without rule", "web", `{"Cmd":["pg_dump"]}`, http.StatusForbidden},
+		{"Missing container", "gone", `{"Cmd":["pg_dump"]}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := created
+			resp, err := http.Post(srv.URL+"/v1.43/containers/"+tt.container+"/exec", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			body := readAll(t, resp)
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d (%s)", tt.expectedStatus, resp.StatusCode, body)
+			}
+			if (created > before) != (tt.expectedStatus == http.StatusCreated) {
+				t.Errorf("Unexpected exec creation count %d", created-before)
+			}
+		})
+	}
+}
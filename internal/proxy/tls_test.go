@@ -0,0 +1,276 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dockershield/config"
+	"dockershield/internal/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testPKI is a throw-away CA issuing the daemon and client certificates
+type testPKI struct {
+	dir    string
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	pool   *x509.CertPool
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+
+	pki := &testPKI{dir: t.TempDir(), caCert: cert, caKey: key, pool: x509.NewCertPool()}
+	pki.pool.AddCert(cert)
+	writePEM(t, filepath.Join(pki.dir, "ca.pem"), "CERTIFICATE", der)
+	return pki
+}
+
+// issue creates a certificate signed by the CA and writes <name>.pem and <name>-key.pem
+func (p *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage, dnsNames ...string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.caCert, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	writePEM(t, filepath.Join(p.dir, name+".pem"), "CERTIFICATE", der)
+	writePEM(t, filepath.Join(p.dir, name+"-key.pem"), "EC PRIVATE KEY", keyDER)
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(p.dir, name+".pem"), filepath.Join(p.dir, name+"-key.pem"))
+	if err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+	return cert
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// newTLSDaemon starts a fake --tlsverify Docker daemon requiring a client certificate
+func newTLSDaemon(t *testing.T, pki *testPKI) string {
+	t.Helper()
+
+	daemon := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("client=" + r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	daemon.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pki.issue(t, "daemon", x509.ExtKeyUsageServerAuth, "docker.internal")},
+		ClientCAs:    pki.pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	daemon.StartTLS()
+	t.Cleanup(daemon.Close)
+
+	return "tcp://" + strings.TrimPrefix(daemon.URL, "https://")
+}
+
+func TestProxyRequestTLSUpstream(t *testing.T) {
+	pki := newTestPKI(t)
+	dockerSocket := newTLSDaemon(t, pki)
+	pki.issue(t, "proxy", x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name           string
+		dockerTLS      *config.DockerTLS
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Client certificate and server name verified",
+			dockerTLS: &config.DockerTLS{
+				CAFile:     filepath.Join(pki.dir, "ca.pem"),
+				CertFile:   filepath.Join(pki.dir, "proxy.pem"),
+				KeyFile:    filepath.Join(pki.dir, "proxy-key.pem"),
+				ServerName: "docker.internal",
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "client=proxy",
+		},
+		{
+			name: "Server name mismatch rejected",
+			dockerTLS: &config.DockerTLS{
+				CAFile:     filepath.Join(pki.dir, "ca.pem"),
+				CertFile:   filepath.Join(pki.dir, "proxy.pem"),
+				KeyFile:    filepath.Join(pki.dir, "proxy-key.pem"),
+				ServerName: "other.internal",
+			},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			name: "Missing client certificate rejected",
+			dockerTLS: &config.DockerTLS{
+				CAFile:     filepath.Join(pki.dir, "ca.pem"),
+				ServerName: "docker.internal",
+			},
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := mustNewHandler(t, &config.Config{DockerSocket: dockerSocket, DockerTLS: tt.dockerTLS})
+			srv := newTestServer(t, handler)
+
+			resp, err := http.Get(srv.URL + "/v1.41/info")
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+			if tt.expectedBody != "" {
+				body := readAll(t, resp)
+				if body != tt.expectedBody {
+					t.Errorf("Expected body '%s', got '%s'", tt.expectedBody, body)
+				}
+			}
+		})
+	}
+}
+
+func TestNewHandlerInvalidTLS(t *testing.T) {
+	_, err := NewHandler(&config.Config{
+		DockerSocket: "tcp://127.0.0.1:2376",
+		DockerTLS:    &config.DockerTLS{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	})
+	if err == nil {
+		t.Error("Expected an error when the CA file cannot be loaded")
+	}
+}
+
+func TestProxyRequestMutualTLSListener(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	pki := newTestPKI(t)
+	pki.issue(t, "listener", x509.ExtKeyUsageServerAuth, "proxy.internal")
+	alice := pki.issue(t, "alice", x509.ExtKeyUsageClientAuth)
+	mallory := newTestPKI(t).issue(t, "mallory", x509.ExtKeyUsageClientAuth)
+
+	socket := newUnixUpstream(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	}))
+
+	tests := []struct {
+		name         string
+		clientAuth   string
+		clientCert   *tls.Certificate
+		expectError  bool
+		expectedUser string
+	}{
+		{"Trusted certificate", "", &alice, false, "alice"},
+		{"Missing certificate rejected", "", nil, true, ""},
+		{"Untrusted certificate rejected", config.ClientAuthRequire, &mallory, true, ""},
+		{"Optional certificate", config.ClientAuthOptional, nil, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listenTLS := &config.ListenTLS{
+				CertFile:     filepath.Join(pki.dir, "listener.pem"),
+				KeyFile:      filepath.Join(pki.dir, "listener-key.pem"),
+				ClientCAFile: filepath.Join(pki.dir, "ca.pem"),
+				ClientAuth:   tt.clientAuth,
+			}
+			serverTLS, err := listenTLS.ServerConfig()
+			if err != nil {
+				t.Fatalf("Failed to build server TLS config: %v", err)
+			}
+
+			user := ""
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if cert := identity.ClientCertificate(c.Request); cert != nil {
+					user = cert.Subject.CommonName
+				}
+			})
+			router.Any("/*path", mustNewHandler(t, &config.Config{DockerSocket: socket}).ProxyRequest)
+
+			srv := httptest.NewUnstartedServer(router)
+			srv.TLS = serverTLS
+			srv.StartTLS()
+			defer srv.Close()
+
+			clientTLS := &tls.Config{RootCAs: pki.pool, ServerName: "proxy.internal", MinVersion: tls.VersionTLS12}
+			if tt.clientCert != nil {
+				clientTLS.Certificates = []tls.Certificate{*tt.clientCert}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+			resp, err := client.Get(srv.URL + "/v1.41/containers/json")
+			if tt.expectError {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("Expected the TLS handshake to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", resp.StatusCode)
+			}
+			if user != tt.expectedUser {
+				t.Errorf("Expected client identity '%s', got '%s'", tt.expectedUser, user)
+			}
+		})
+	}
+}
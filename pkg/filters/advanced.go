@@ -12,6 +12,22 @@ type AdvancedFilter struct {
 	Containers *ContainerFilter `json:"containers,omitempty"`
 	Networks   *NetworkFilter   `json:"networks,omitempty"`
 	Images     *ImageFilter     `json:"images,omitempty"`
+
+	// Opérations sur les conteneurs existants
+	Updates       *UpdateFilter        `json:"updates,omitempty"`
+	RestartPolicy *RestartPolicyFilter `json:"restart_policy,omitempty"`
+	Exec          *ExecFilter          `json:"exec,omitempty"`
+	Rename        *RenameFilter        `json:"rename,omitempty"`
+	Commit        *CommitFilter        `json:"commit,omitempty"`
+
+	// Restreint l'appelant aux objets portant ses labels
+	Ownership *OwnershipFilter `json:"ownership,omitempty"`
+
+	// Restreint les objets visibles dans les listes et les inspections
+	Visibility *VisibilityFilter `json:"visibility,omitempty"`
+
+	// Modifications appliquées aux créations avant leur vérification
+	Mutations *MutationFilter `json:"mutations,omitempty"`
 }
 
 // VolumeFilter définit les règles de filtrage pour les volumes
@@ -21,6 +37,8 @@ type VolumeFilter struct {
 	AllowedPaths   []string `json:"allowed_paths,omitempty"`   // Chemins autorisés (patterns)
 	DeniedPaths    []string `json:"denied_paths,omitempty"`    // Chemins interdits (patterns)
 	AllowedDrivers []string `json:"allowed_drivers,omitempty"` // Drivers autorisés
+
+	Labels *LabelFilter `json:"labels,omitempty"` // Règles sur les labels
 }
 
 // ContainerFilter defines filtering rules for containers
@@ -29,9 +47,39 @@ type ContainerFilter struct {
 	DeniedImages    []string          `json:"denied_images,omitempty"`     // Denied images (patterns)
 	AllowedNames    []string          `json:"allowed_names,omitempty"`     // Allowed names (patterns)
 	DeniedNames     []string          `json:"denied_names,omitempty"`      // Denied names (patterns)
-	RequireLabels   map[string]string `json:"require_labels,omitempty"`    // Required labels
+	RequireLabels   map[string]string `json:"require_labels,omitempty"`    // Required labels (exact values)
+	Labels          *LabelFilter      `json:"labels,omitempty"`            // Label patterns, denied and allowed keys
+	AllowedEnv      []string          `json:"allowed_env,omitempty"`       // Allowed environment variable names (patterns)
+	DeniedEnv       []string          `json:"denied_env,omitempty"`        // Denied environment variable names (patterns)
+	DeniedEnvValues []string          `json:"denied_env_values,omitempty"` // Denied environment variable values (patterns), e.g. credentials
 	DenyPrivileged  bool              `json:"deny_privileged,omitempty"`   // Deny privileged containers
 	DenyHostNetwork bool              `json:"deny_host_network,omitempty"` // Deny host network
+
+	// Linux capabilities (names such as "NET_ADMIN" or "CAP_NET_ADMIN", "ALL" for every capability)
+	AllowedCapabilities []string `json:"allowed_capabilities,omitempty"` // Capabilities CapAdd may contain
+	DeniedCapabilities  []string `json:"denied_capabilities,omitempty"`  // Capabilities CapAdd must not contain
+	RequiredCapDrop     []string `json:"required_cap_drop,omitempty"`    // Capabilities CapDrop must contain
+
+	Devices   *DeviceFilter   `json:"devices,omitempty"`   // Host device access
+	Resources *ResourceFilter `json:"resources,omitempty"` // Mandatory resource limits
+	Ports     *PortFilter     `json:"ports,omitempty"`     // Published host ports
+	User      *UserFilter     `json:"user,omitempty"`      // User the container runs as
+
+	// Namespaces shared with the host or with other containers
+	DenyHostPID             bool     `json:"deny_host_pid,omitempty"`             // Deny PidMode=host
+	DenyHostIPC             bool     `json:"deny_host_ipc,omitempty"`             // Deny IpcMode=host
+	DenyHostUTS             bool     `json:"deny_host_uts,omitempty"`             // Deny UTSMode=host
+	DenyHostUserns          bool     `json:"deny_host_userns,omitempty"`          // Deny UsernsMode=host
+	DenyHostCgroupns        bool     `json:"deny_host_cgroupns,omitempty"`        // Deny CgroupnsMode=host
+	DenyContainerNamespaces bool     `json:"deny_container_namespaces,omitempty"` // Deny container:<id> network, PID and IPC modes
+	ProtectedContainers     []string `json:"protected_containers,omitempty"`      // Containers (names or IDs) whose namespaces cannot be joined
+
+	// Security options (HostConfig.SecurityOpt)
+	DenyUnconfined          bool     `json:"deny_unconfined,omitempty"`           // Deny seccomp/apparmor/systempaths=unconfined and label=disable
+	AllowedSeccompProfiles  []string `json:"allowed_seccomp_profiles,omitempty"`  // Allowed seccomp profiles (patterns)
+	AllowedAppArmorProfiles []string `json:"allowed_apparmor_profiles,omitempty"` // Allowed AppArmor profiles (patterns)
+	AllowedSELinuxLabels    []string `json:"allowed_selinux_labels,omitempty"`    // Allowed SELinux labels, e.g. "type:svirt_apache_t" (patterns)
+	RequireNoNewPrivileges  bool     `json:"require_no_new_privileges,omitempty"` // Require no-new-privileges
 }
 
 // NetworkFilter définit les règles de filtrage pour les réseaux
@@ -39,6 +87,8 @@ type NetworkFilter struct {
 	AllowedNames   []string `json:"allowed_names,omitempty"`   // Noms autorisés (patterns)
 	DeniedNames    []string `json:"denied_names,omitempty"`    // Noms interdits (patterns)
 	AllowedDrivers []string `json:"allowed_drivers,omitempty"` // Drivers autorisés
+
+	Labels *LabelFilter `json:"labels,omitempty"` // Règles sur les labels
 }
 
 // ImageFilter définit les règles de filtrage pour les images
@@ -91,6 +141,25 @@ func (af *AdvancedFilter) CheckVolumeMount(volumeName, hostPath, driver string)
 
 // CheckContainerCreate checks if a container creation is allowed
 func (af *AdvancedFilter) CheckContainerCreate(image, name string, config map[string]interface{}) (bool, string) {
+	// Volume rules apply to the bind mounts and volumes of the container
+	if ok, msg := af.checkContainerMounts(config); !ok {
+		return false, msg
+	}
+
+	// Restart policy rules apply to creations and updates
+	if hostConfig, ok := config["HostConfig"].(map[string]interface{}); ok {
+		policy, _ := hostConfig["RestartPolicy"].(map[string]interface{})
+		if ok, msg := checkRestartPolicy(af.RestartPolicy, policy); !ok {
+			return false, msg
+		}
+	}
+
+	// The caller must be able to operate on the containers it creates
+	labels, _ := config["Labels"].(map[string]interface{})
+	if ok, msg := checkOwnerLabels(af.Ownership, labels); !ok {
+		return false, msg
+	}
+
 	if af.Containers == nil {
 		return true, ""
 	}
@@ -117,16 +186,92 @@ func (af *AdvancedFilter) CheckContainerCreate(image, name string, config map[st
 		}
 	}
 
+	// Check environment variables
+	if ok, msg := checkEnv(cf, config); !ok {
+		return false, msg
+	}
+
 	// Check HostConfig settings
 	if ok, msg := checkHostConfig(cf, config); !ok {
 		return false, msg
 	}
 
+	// Check namespaces shared with the host or other containers
+	if ok, msg := checkNamespaces(cf, config); !ok {
+		return false, msg
+	}
+
+	// Check the user
+	if ok, msg := checkUser(cf.User, config); !ok {
+		return false, msg
+	}
+
+	// Check security options
+	if ok, msg := checkSecurityOptions(cf, config); !ok {
+		return false, msg
+	}
+
+	// Check capabilities
+	if ok, msg := checkCapabilities(cf, config); !ok {
+		return false, msg
+	}
+
+	// Check devices
+	if ok, msg := checkDevices(cf.Devices, config); !ok {
+		return false, msg
+	}
+
+	// Check published ports
+	if ok, msg := checkPorts(cf.Ports, config); !ok {
+		return false, msg
+	}
+
+	// Check resource limits
+	if ok, msg := checkResources(cf.Resources, config); !ok {
+		return false, msg
+	}
+
 	// Check required labels
 	if ok, msg := checkRequiredLabels(cf.RequireLabels, config); !ok {
 		return false, msg
 	}
 
+	// Check label rules
+	if ok, msg := checkLabels(cf.Labels, labels); !ok {
+		return false, msg
+	}
+
+	return true, ""
+}
+
+// checkEnv validates Config.Env ("KEY=value" entries).
+// Reasons name the variable, never its value, as values may hold secrets.
+func checkEnv(cf *ContainerFilter, config map[string]interface{}) (bool, string) {
+	if len(cf.AllowedEnv) == 0 && len(cf.DeniedEnv) == 0 && len(cf.DeniedEnvValues) == 0 {
+		return true, ""
+	}
+
+	entries, _ := config["Env"].([]interface{})
+	for _, entry := range entries {
+		variable, ok := entry.(string)
+		if !ok {
+			continue
+		}
+		key, value, _ := strings.Cut(variable, "=")
+
+		if ok, msg := checkDeniedList(cf.DeniedEnv, key, "environment variable is denied"); !ok {
+			return false, msg
+		}
+		if ok, msg := checkAllowedList(cf.AllowedEnv, key, "environment variable not in allowed list"); !ok {
+			return false, msg
+		}
+		for _, pattern := range cf.DeniedEnvValues {
+			if matched, _ := regexp.MatchString(pattern, value); matched {
+				return false, "environment variable value is denied: " + key
+			}
+		}
+	}
+
 	return true, ""
 }
 
@@ -0,0 +1,79 @@
+package filters
+
+import (
+	"strings"
+)
+
+// allCapabilities stands for every capability in CapAdd and CapDrop
+const allCapabilities = "ALL"
+
+// checkCapabilities validates HostConfig.CapAdd and HostConfig.CapDrop.
+// Adding ALL is only allowed when ALL is explicitly allowed and no capability
+// is denied; dropping ALL satisfies every required drop.
+func checkCapabilities(cf *ContainerFilter, config map[string]interface{}) (bool, string) {
+	if len(cf.AllowedCapabilities) == 0 && len(cf.DeniedCapabilities) == 0 && len(cf.RequiredCapDrop) == 0 {
+		return true, ""
+	}
+
+	var capAdd, capDrop []string
+	if hostConfig, ok := config["HostConfig"].(map[string]interface{}); ok {
+		capAdd = capabilityList(hostConfig["CapAdd"])
+		capDrop = capabilityList(hostConfig["CapDrop"])
+	}
+
+	denied := normalizeCapabilities(cf.DeniedCapabilities)
+	allowed := normalizeCapabilities(cf.AllowedCapabilities)
+
+	for _, capability := range capAdd {
+		if capability == allCapabilities && len(denied) > 0 {
+			return false, "capability ALL is denied (includes denied capabilities)"
+		}
+		if contains(denied, capability) || contains(denied, allCapabilities) {
+			return false, "capability is denied: " + capability
+		}
+		if len(allowed) > 0 && !contains(allowed, capability) {
+			return false, "capability not in allowed list: " + capability
+		}
+	}
+
+	if !contains(capDrop, allCapabilities) {
+		for _, required := range normalizeCapabilities(cf.RequiredCapDrop) {
+			if !contains(capDrop, required) {
+				return false, "capability must be dropped: " + required
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// capabilityList reads a CapAdd or CapDrop value of the request body
+func capabilityList(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	capabilities := make([]string, 0, len(items))
+	for _, item := range items {
+		if capability, ok := item.(string); ok {
+			capabilities = append(capabilities, normalizeCapability(capability))
+		}
+	}
+	return capabilities
+}
+
+// normalizeCapabilities normalizes a configured capability list
+func normalizeCapabilities(capabilities []string) []string {
+	result := make([]string, 0, len(capabilities))
+	for _, capability := range capabilities {
+		result = append(result, normalizeCapability(capability))
+	}
+	return result
+}
+
+// normalizeCapability returns the capability name as Docker does: upper case without "CAP_"
+func normalizeCapability(capability string) string {
+	capability = strings.ToUpper(strings.TrimSpace(capability))
+	return strings.TrimPrefix(capability, "CAP_")
+}
@@ -0,0 +1,65 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreateCapabilities(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			AllowedCapabilities: []string{"NET_BIND_SERVICE", "CAP_CHOWN"},
+			DeniedCapabilities:  []string{"SYS_ADMIN"},
+			RequiredCapDrop:     []string{"net_raw"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		capAdd        []interface{}
+		capDrop       []interface{}
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Allowed capability", []interface{}{"NET_BIND_SERVICE"}, []interface{}{"NET_RAW"}, true, ""},
+		{"Allowed capability with prefix", []interface{}{"cap_chown"}, []interface{}{"CAP_NET_RAW"}, true, ""},
+		{"Denied capability", []interface{}{"CAP_SYS_ADMIN"}, []interface{}{"NET_RAW"}, false, "capability is denied: SYS_ADMIN"},
+		{"Capability not allowed", []interface{}{"NET_ADMIN"}, []interface{}{"NET_RAW"}, false, "capability not in allowed list: NET_ADMIN"},
+		{"Add ALL", []interface{}{"ALL"}, []interface{}{"NET_RAW"}, false, "capability ALL is denied (includes denied capabilities)"},
+		{"Required drop missing", nil, nil, false, "capability must be dropped: NET_RAW"},
+		{"Drop ALL", nil, []interface{}{"ALL"}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostConfig := map[string]interface{}{}
+			if tt.capAdd != nil {
+				hostConfig["CapAdd"] = tt.capAdd
+			}
+			if tt.capDrop != nil {
+				hostConfig["CapDrop"] = tt.capDrop
+			}
+
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", map[string]interface{}{"HostConfig": hostConfig})
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+
+	t.Run("ALL explicitly allowed", func(t *testing.T) {
+		allowAll := &AdvancedFilter{Containers: &ContainerFilter{AllowedCapabilities: []string{"ALL"}}}
+		config := map[string]interface{}{"HostConfig": map[string]interface{}{"CapAdd": []interface{}{"ALL"}}}
+		if allowed, reason := allowAll.CheckContainerCreate("nginx", "web", config); !allowed {
+			t.Errorf("Expected ALL to be allowed, got: %s", reason)
+		}
+	})
+
+	t.Run("ALL denied blocks any capability", func(t *testing.T) {
+		denyAll := &AdvancedFilter{Containers: &ContainerFilter{DeniedCapabilities: []string{"ALL"}}}
+		config := map[string]interface{}{"HostConfig": map[string]interface{}{"CapAdd": []interface{}{"CHOWN"}}}
+		if allowed, _ := denyAll.CheckContainerCreate("nginx", "web", config); allowed {
+			t.Error("Expected every capability to be denied")
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package filters
+
+import (
+	"path"
+	"strings"
+)
+
+// DeviceFilter defines the host devices a container may access
+// (HostConfig.Devices, DeviceRequests and DeviceCgroupRules)
+type DeviceFilter struct {
+	DenyAll            bool     `json:"deny_all,omitempty"`             // Deny any device, device request or cgroup rule
+	AllowedPaths       []string `json:"allowed_paths,omitempty"`        // Allowed host device paths (patterns)
+	DeniedPaths        []string `json:"denied_paths,omitempty"`         // Denied host device paths (patterns)
+	AllowedCgroupRules []string `json:"allowed_cgroup_rules,omitempty"` // Allowed device cgroup rules (patterns), others are denied
+	AllowedDrivers     []string `json:"allowed_drivers,omitempty"`      // Allowed device request drivers, e.g. "nvidia"
+}
+
+// checkDevices validates the devices requested by a container
+func checkDevices(df *DeviceFilter, config map[string]interface{}) (bool, string) {
+	if df == nil {
+		return true, ""
+	}
+
+	hostConfig, ok := config["HostConfig"].(map[string]interface{})
+	if !ok {
+		return true, ""
+	}
+
+	devices, _ := hostConfig["Devices"].([]interface{})
+	requests, _ := hostConfig["DeviceRequests"].([]interface{})
+	cgroupRules, _ := hostConfig["DeviceCgroupRules"].([]interface{})
+
+	if df.DenyAll && (len(devices) > 0 || len(requests) > 0 || len(cgroupRules) > 0) {
+		return false, "device access is denied"
+	}
+
+	for _, entry := range devices {
+		device, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		hostPath, _ := device["PathOnHost"].(string)
+		if hostPath == "" {
+			continue
+		}
+		hostPath = path.Clean(hostPath)
+
+		if ok, msg := checkDeniedList(df.DeniedPaths, hostPath, "device is denied"); !ok {
+			return false, msg
+		}
+		if ok, msg := checkAllowedList(df.AllowedPaths, hostPath, "device not in allowed list"); !ok {
+			return false, msg
+		}
+	}
+
+	// Cgroup rules grant access by device number, bypassing the path rules:
+	// once devices are filtered, they must be explicitly allowed
+	for _, entry := range cgroupRules {
+		rule, ok := entry.(string)
+		if !ok {
+			continue
+		}
+		rule = strings.TrimSpace(rule)
+		if len(df.AllowedCgroupRules) == 0 {
+			return false, "device cgroup rule not allowed: " + rule
+		}
+		if ok, msg := checkAllowedList(df.AllowedCgroupRules, rule, "device cgroup rule not allowed"); !ok {
+			return false, msg
+		}
+	}
+
+	for _, entry := range requests {
+		request, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		driver, _ := request["Driver"].(string)
+		if len(df.AllowedDrivers) > 0 && !contains(df.AllowedDrivers, driver) {
+			return false, "device request driver not allowed: " + driver
+		}
+	}
+
+	return true, ""
+}
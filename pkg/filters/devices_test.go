@@ -0,0 +1,105 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreateDevices(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			Devices: &DeviceFilter{
+				AllowedPaths:       []string{`^/dev/(dri/.*|fuse)$`},
+				DeniedPaths:        []string{`^/dev/(mem|kmem|port)$`},
+				AllowedCgroupRules: []string{`^c 10:229 rwm$`},
+				AllowedDrivers:     []string{"nvidia"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{
+			name:          "No devices",
+			body:          `{"Image":"nginx","HostConfig":{}}`,
+			expectAllowed: true,
+		},
+		{
+			name:          "Allowed device",
+			body:          `{"Image":"nginx","HostConfig":{"Devices":[{"PathOnHost":"/dev/dri/renderD128","PathInContainer":"/dev/dri/renderD128","CgroupPermissions":"rwm"}]}}`,
+			expectAllowed: true,
+		},
+		{
+			name:          "Denied device",
+			body:          `{"Image":"nginx","HostConfig":{"Devices":[{"PathOnHost":"/dev/mem","PathInContainer":"/dev/mem","CgroupPermissions":"r"}]}}`,
+			expectAllowed: false,
+			expectReason:  "device is denied: /dev/mem",
+		},
+		{
+			name:          "Device path normalized",
+			body:          `{"Image":"nginx","HostConfig":{"Devices":[{"PathOnHost":"/dev/dri/../mem","PathInContainer":"/dev/x"}]}}`,
+			expectAllowed: false,
+			expectReason:  "device is denied: /dev/mem",
+		},
+		{
+			name:          "Device not in allowed list",
+			body:          `{"Image":"nginx","HostConfig":{"Devices":[{"PathOnHost":"/dev/sda","PathInContainer":"/dev/sda"}]}}`,
+			expectAllowed: false,
+			expectReason:  "device not in allowed list: /dev/sda",
+		},
+		{
+			name:          "Allowed cgroup rule",
+			body:          `{"Image":"nginx","HostConfig":{"DeviceCgroupRules":["c 10:229 rwm"]}}`,
+			expectAllowed: true,
+		},
+		{
+			name:          "Wildcard cgroup rule",
+			body:          `{"Image":"nginx","HostConfig":{"DeviceCgroupRules":["a *:* rwm"]}}`,
+			expectAllowed: false,
+			expectReason:  "device cgroup rule not allowed: a *:* rwm",
+		},
+		{
+			name:          "Allowed GPU request",
+			body:          `{"Image":"nginx","HostConfig":{"DeviceRequests":[{"Driver":"nvidia","Count":-1,"Capabilities":[["gpu"]]}]}}`,
+			expectAllowed: true,
+		},
+		{
+			name:          "Device request driver not allowed",
+			body:          `{"Image":"nginx","HostConfig":{"DeviceRequests":[{"Driver":"","Count":-1,"Capabilities":[["gpu"]]}]}}`,
+			expectAllowed: false,
+			expectReason:  "device request driver not allowed: ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", containerConfig(t, tt.body))
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckContainerCreateDenyAllDevices(t *testing.T) {
+	filter := &AdvancedFilter{Containers: &ContainerFilter{Devices: &DeviceFilter{DenyAll: true}}}
+
+	bodies := []string{
+		`{"Image":"nginx","HostConfig":{"Devices":[{"PathOnHost":"/dev/fuse","PathInContainer":"/dev/fuse"}]}}`,
+		`{"Image":"nginx","HostConfig":{"DeviceRequests":[{"Driver":"nvidia","Count":1}]}}`,
+		`{"Image":"nginx","HostConfig":{"DeviceCgroupRules":["c 10:229 rwm"]}}`,
+	}
+	for _, body := range bodies {
+		if allowed, _ := filter.CheckContainerCreate("nginx", "web", containerConfig(t, body)); allowed {
+			t.Errorf("Expected device access to be denied for %s", body)
+		}
+	}
+
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", containerConfig(t, `{"Image":"nginx","HostConfig":{"Devices":[]}}`)); !allowed {
+		t.Errorf("Expected container without devices to be allowed, got: %s", reason)
+	}
+}
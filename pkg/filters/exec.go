@@ -0,0 +1,199 @@
+package filters
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ExecFilter defines the commands that may be run in a container
+// (POST /containers/{id}/exec, i.e. docker exec)
+type ExecFilter struct {
+	DenyPrivileged bool `json:"deny_privileged,omitempty"` // Deny docker exec --privileged
+	DenyRoot       bool `json:"deny_root,omitempty"`       // Deny docker exec --user root or --user 0
+
+	// Rules restrict exec to known commands: the first rule whose scope matches
+	// the target container applies, and exec is denied in other containers
+	Rules []ExecRule `json:"rules,omitempty"`
+}
+
+// ExecRule allows some commands in the containers matching its scope.
+// Every scope field set must match; a rule without scope matches every container.
+type ExecRule struct {
+	Containers []string          `json:"containers,omitempty"` // Target container names (patterns)
+	Images     []string          `json:"images,omitempty"`     // Target container images (patterns)
+	Labels     map[string]string `json:"labels,omitempty"`     // Target container labels, with a value pattern
+
+	AllowedCommands    []string `json:"allowed_commands,omitempty"`     // Allowed commands (patterns on Cmd joined by spaces)
+	AllowedUsers       []string `json:"allowed_users,omitempty"`        // Allowed --user overrides (patterns); none by default
+	AllowedEnv         []string `json:"allowed_env,omitempty"`          // Allowed --env variable names (patterns); none by default
+	AllowedWorkingDirs []string `json:"allowed_working_dirs,omitempty"` // Allowed --workdir overrides (patterns); none by default
+	AllowPrivileged    bool     `json:"allow_privileged,omitempty"`     // Allow --privileged
+	AllowTty           bool     `json:"allow_tty,omitempty"`            // Allow -t
+	AllowStdin         bool     `json:"allow_stdin,omitempty"`          // Allow -i
+}
+
+// ExecTarget describes the container an exec instance is created in
+type ExecTarget struct {
+	Name   string
+	Image  string
+	Labels map[string]string
+}
+
+// ExecTargetNeeded reports whether CheckExecCreate needs the target container
+func (af *AdvancedFilter) ExecTargetNeeded() bool {
+	return af.Exec != nil && len(af.Exec.Rules) > 0
+}
+
+// CheckExecCreate checks if an exec instance may be created in the target container.
+// Without --user, the command runs as the container user, which the
+// container filters already check on creation. Variables set with --env go
+// through the container environment rules.
+func (af *AdvancedFilter) CheckExecCreate(target *ExecTarget, config map[string]interface{}) (bool, string) {
+	if af.Containers != nil {
+		if ok, msg := checkEnv(af.Containers, config); !ok {
+			return false, msg
+		}
+	}
+
+	if af.Exec == nil {
+		return true, ""
+	}
+
+	ef := af.Exec
+	user, _ := config["User"].(string)
+	user = strings.TrimSpace(user)
+	privileged, _ := config["Privileged"].(bool)
+
+	if privileged && ef.DenyPrivileged {
+		return false, "privileged exec is denied"
+	}
+
+	if ef.DenyRoot {
+		name, _, _ := strings.Cut(user, ":")
+		if name == "root" || isRootID(name) {
+			return false, "exec user is root: " + user
+		}
+	}
+
+	if len(ef.Rules) == 0 {
+		return true, ""
+	}
+	if target == nil {
+		return false, "exec target container is unknown"
+	}
+
+	rule := matchExecRule(ef.Rules, target)
+	if rule == nil {
+		return false, "exec is not allowed in container: " + target.Name
+	}
+
+	return checkExecRule(rule, config, user, privileged)
+}
+
+// matchExecRule returns the first rule whose scope matches the target container
+func matchExecRule(rules []ExecRule, target *ExecTarget) *ExecRule {
+	name := strings.TrimPrefix(target.Name, "/")
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Containers) > 0 && !matchesAny(rule.Containers, name) {
+			continue
+		}
+		if len(rule.Images) > 0 && !matchesAny(rule.Images, target.Image) {
+			continue
+		}
+		if !labelsMatch(rule.Labels, target.Labels) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// checkExecRule validates an exec instance against the rule of its container.
+// Only the program name is reported: arguments may hold secrets.
+func checkExecRule(rule *ExecRule, config map[string]interface{}, user string, privileged bool) (bool, string) {
+	if privileged && !rule.AllowPrivileged {
+		return false, "privileged exec is denied"
+	}
+	if user != "" && !matchesAny(rule.AllowedUsers, user) {
+		return false, "exec user override is denied: " + user
+	}
+	if tty, _ := config["Tty"].(bool); tty && !rule.AllowTty {
+		return false, "exec with a TTY is denied"
+	}
+	if stdin, _ := config["AttachStdin"].(bool); stdin && !rule.AllowStdin {
+		return false, "exec with stdin is denied"
+	}
+	if workingDir, _ := config["WorkingDir"].(string); workingDir != "" && !matchesAny(rule.AllowedWorkingDirs, workingDir) {
+		return false, "exec working directory override is denied: " + workingDir
+	}
+
+	env, ok := stringList(config["Env"])
+	if !ok {
+		return false, "exec environment must be a list of strings"
+	}
+	for _, variable := range env {
+		// Only the name is reported: values may hold secrets
+		if key, _, _ := strings.Cut(variable, "="); !matchesAny(rule.AllowedEnv, key) {
+			return false, "exec environment variable is denied: " + key
+		}
+	}
+
+	cmd, ok := stringList(config["Cmd"])
+	if !ok {
+		return false, "exec command must be a list of strings"
+	}
+	if len(cmd) == 0 {
+		return false, "exec command is required"
+	}
+	if len(rule.AllowedCommands) > 0 && !matchesAny(rule.AllowedCommands, strings.Join(cmd, " ")) {
+		return false, "exec command not allowed: " + cmd[0]
+	}
+
+	return true, ""
+}
+
+// stringList reads a string array of an exec body (Cmd, Env). A missing value
+// is an empty list; any other type, or a non-string item, is invalid.
+func stringList(value interface{}) ([]string, bool) {
+	if value == nil {
+		return nil, true
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, s)
+	}
+	return list, true
+}
+
+// labelsMatch reports whether every expected label is set with a matching value
+func labelsMatch(expected, labels map[string]string) bool {
+	for key, pattern := range expected {
+		value, ok := labels[key]
+		if !ok {
+			return false
+		}
+		if matched, _ := regexp.MatchString(pattern, value); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether the value matches one of the patterns
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := regexp.MatchString(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package filters
+
+import "testing"
+
+func TestCheckExecCreateRules(t *testing.T) {
+	filter := &AdvancedFilter{
+		Exec: &ExecFilter{
+			Rules: []ExecRule{
+				{
+					Containers:      []string{"^postgres-"},
+					AllowedCommands: []string{"^pg_dump( |$)", "^pg_isready$"},
+					AllowedUsers:    []string{"^postgres$"},
+				},
+				{
+					Labels:          map[string]string{"exec.healthcheck": "^true$"},
+					AllowedCommands: []string{"^/healthcheck\\.sh$"},
+				},
+				{
+					Images:             []string{"^registry.local/debug:"},
+					AllowedEnv:         []string{"^TERM$"},
+					AllowedWorkingDirs: []string{"^/tmp$"},
+					AllowTty:           true,
+					AllowStdin:         true,
+				},
+			},
+		},
+	}
+
+	postgres := &ExecTarget{Name: "/postgres-main", Image: "postgres:16"}
+	web := &ExecTarget{Name: "/web", Image: "nginx", Labels: map[string]string{"exec.healthcheck": "true"}}
+	debug := &ExecTarget{Name: "/debug", Image: "registry.local/debug:1.0"}
+	other := &ExecTarget{Name: "/cache", Image: "redis", Labels: map[string]string{"exec.healthcheck": "false"}}
+
+	tests := []struct {
+		name          string
+		target        *ExecTarget
+		config        string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Allowed dump", postgres, `{"Cmd":["pg_dump","-U","app","--password=secret"]}`, true, ""},
+		{"Allowed user override", postgres, `{"Cmd":["pg_isready"],"User":"postgres"}`, true, ""},
+		{"Shell in database", postgres, `{"Cmd":["sh","-c","cat /etc/shadow"]}`, false, "exec command not allowed: sh"},
+		{"Root override", postgres, `{"Cmd":["pg_isready"],"User":"root"}`, false, "exec user override is denied: root"},
+		{"Interactive session", postgres, `{"Cmd":["pg_isready"],"Tty":true}`, false, "exec with a TTY is denied"},
+		{"Stdin attached", postgres, `{"Cmd":["pg_isready"],"AttachStdin":true}`, false, "exec with stdin is denied"},
+		{"Privileged", postgres, `{"Cmd":["pg_isready"],"Privileged":true}`, false, "privileged exec is denied"},
+		{"Health check by label", web, `{"Cmd":["/healthcheck.sh"]}`, true, ""},
+		{"Other command by label", web, `{"Cmd":["/healthcheck.sh","; id"]}`, false, "exec command not allowed: /healthcheck.sh"},
+		{"Debug image shell", debug, `{"Cmd":["sh"],"Tty":true,"AttachStdin":true}`, true, ""},
+		{"Empty command", debug, `{"Cmd":[]}`, false, "exec command is required"},
+		{"Non-string command item", web, `{"Cmd":["/healthcheck.sh",{"arg":"; id"}]}`, false, "exec command must be a list of strings"},
+		{"Command string", web, `{"Cmd":"/healthcheck.sh"}`, false, "exec command must be a list of strings"},
+		{"Environment override", postgres, `{"Cmd":["pg_isready"],"Env":["PGPASSWORD=secret"]}`, false, "exec environment variable is denied: PGPASSWORD"},
+		{"Allowed environment", debug, `{"Cmd":["sh"],"Env":["TERM=xterm"]}`, true, ""},
+		{"Other environment", debug, `{"Cmd":["sh"],"Env":["TERM=xterm","LD_PRELOAD=/tmp/x.so"]}`, false, "exec environment variable is denied: LD_PRELOAD"},
+		{"Non-string environment item", debug, `{"Cmd":["sh"],"Env":[1]}`, false, "exec environment must be a list of strings"},
+		{"Working directory override", postgres, `{"Cmd":["pg_isready"],"WorkingDir":"/var/lib/postgresql"}`, false, "exec working directory override is denied: /var/lib/postgresql"},
+		{"Allowed working directory", debug, `{"Cmd":["sh"],"WorkingDir":"/tmp"}`, true, ""},
+		{"No matching rule", other, `{"Cmd":["redis-cli","ping"]}`, false, "exec is not allowed in container: /cache"},
+		{"Unknown target", nil, `{"Cmd":["pg_isready"]}`, false, "exec target container is unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckExecCreate(tt.target, containerConfig(t, tt.config))
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestExecTargetNeeded(t *testing.T) {
+	if (&AdvancedFilter{Exec: &ExecFilter{DenyRoot: true}}).ExecTargetNeeded() {
+		t.Error("Expected no target lookup without exec rules")
+	}
+	if !(&AdvancedFilter{Exec: &ExecFilter{Rules: []ExecRule{{}}}}).ExecTargetNeeded() {
+		t.Error("Expected a target lookup with exec rules")
+	}
+}
+
+func TestCheckExecCreateEnv(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{DeniedEnv: []string{"^LD_PRELOAD$"}, DeniedEnvValues: []string{"^AKIA"}},
+	}
+
+	tests := []struct {
+		name         string
+		config       string
+		expectReason string
+	}{
+		{"Plain exec", `{"Cmd":["ls"]}`, ""},
+		{"Allowed variable", `{"Cmd":["ls"],"Env":["TERM=xterm"]}`, ""},
+		{"Denied variable", `{"Cmd":["ls"],"Env":["LD_PRELOAD=/tmp/x.so"]}`, "environment variable is denied: LD_PRELOAD"},
+		{"Denied value", `{"Cmd":["ls"],"Env":["KEY=AKIA123"]}`, "environment variable value is denied: KEY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, reason := filter.CheckExecCreate(nil, containerConfig(t, tt.config)); reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package filters
+
+import (
+	"regexp"
+	"sort"
+)
+
+// LabelFilter defines the labels a container, volume or network may carry.
+// Keys and values are matched as patterns.
+type LabelFilter struct {
+	Required      map[string]string `json:"required,omitempty"`       // Labels that must be set, with a value pattern
+	AllowedValues map[string]string `json:"allowed_values,omitempty"` // Value pattern of a label, when it is set
+	DeniedKeys    []string          `json:"denied_keys,omitempty"`    // Label keys that must not be set (patterns), e.g. "^traefik\\."
+	AllowedKeys   []string          `json:"allowed_keys,omitempty"`   // Only label keys allowed (patterns)
+}
+
+// CheckVolumeLabels checks the labels of a volume creation
+func (af *AdvancedFilter) CheckVolumeLabels(labels map[string]interface{}) (bool, string) {
+	if ok, msg := checkOwnerLabels(af.Ownership, labels); !ok {
+		return false, msg
+	}
+	if af.Volumes == nil {
+		return true, ""
+	}
+	return checkLabels(af.Volumes.Labels, labels)
+}
+
+// CheckNetworkLabels checks the labels of a network creation
+func (af *AdvancedFilter) CheckNetworkLabels(labels map[string]interface{}) (bool, string) {
+	if ok, msg := checkOwnerLabels(af.Ownership, labels); !ok {
+		return false, msg
+	}
+	if af.Networks == nil {
+		return true, ""
+	}
+	return checkLabels(af.Networks.Labels, labels)
+}
+
+// checkLabels validates the labels of a creation request
+func checkLabels(lf *LabelFilter, labels map[string]interface{}) (bool, string) {
+	if lf == nil {
+		return true, ""
+	}
+
+	// Check the labels in a stable order, so the reason does not change between calls
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if ok, msg := checkDeniedList(lf.DeniedKeys, key, "label is denied"); !ok {
+			return false, msg
+		}
+		if ok, msg := checkAllowedList(lf.AllowedKeys, key, "label not in allowed list"); !ok {
+			return false, msg
+		}
+		if pattern, ok := lf.AllowedValues[key]; ok {
+			value, _ := labels[key].(string)
+			if matched, _ := regexp.MatchString(pattern, value); !matched {
+				return false, "label value not allowed: " + key
+			}
+		}
+	}
+
+	required := make([]string, 0, len(lf.Required))
+	for key := range lf.Required {
+		required = append(required, key)
+	}
+	sort.Strings(required)
+
+	for _, key := range required {
+		value, ok := labels[key].(string)
+		if !ok {
+			return false, "required label missing: " + key
+		}
+		if matched, _ := regexp.MatchString(lf.Required[key], value); !matched {
+			return false, "required label mismatch: " + key
+		}
+	}
+
+	return true, ""
+}
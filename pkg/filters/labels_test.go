@@ -0,0 +1,70 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreateLabels(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			Labels: &LabelFilter{
+				Required:      map[string]string{"team": "^(web|data)$"},
+				AllowedValues: map[string]string{"com.docker.compose.project": "^ci-"},
+				DeniedKeys:    []string{`^traefik\.`},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		labels        string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Required label matching", `{"team":"web"}`, true, ""},
+		{"Owned compose project", `{"team":"data","com.docker.compose.project":"ci-1234"}`, true, ""},
+		{"No labels", `null`, false, "required label missing: team"},
+		{"Required label mismatch", `{"team":"ops"}`, false, "required label mismatch: team"},
+		{"Denied key", `{"team":"web","traefik.http.routers.admin.rule":"Host(\"admin\")"}`, false, "label is denied: traefik.http.routers.admin.rule"},
+		{"Foreign compose project", `{"team":"web","com.docker.compose.project":"prod"}`, false, "label value not allowed: com.docker.compose.project"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := containerConfig(t, `{"Image":"nginx","Labels":`+tt.labels+`}`)
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", config)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckVolumeAndNetworkLabels(t *testing.T) {
+	labels := &LabelFilter{AllowedKeys: []string{"^team$", `^com\.docker\.compose\.`}}
+	filter := &AdvancedFilter{
+		Volumes:  &VolumeFilter{Labels: labels},
+		Networks: &NetworkFilter{Labels: labels},
+	}
+
+	allowedLabels := map[string]interface{}{"team": "web", "com.docker.compose.volume": "data"}
+	if allowed, reason := filter.CheckVolumeLabels(allowedLabels); !allowed {
+		t.Errorf("Expected volume labels to be allowed, got: %s", reason)
+	}
+	if allowed, reason := filter.CheckNetworkLabels(allowedLabels); !allowed {
+		t.Errorf("Expected network labels to be allowed, got: %s", reason)
+	}
+
+	otherLabels := map[string]interface{}{"team": "web", "owner": "mallory"}
+	if allowed, reason := filter.CheckVolumeLabels(otherLabels); allowed || reason != "label not in allowed list: owner" {
+		t.Errorf("Expected volume label owner to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+	if allowed, reason := filter.CheckNetworkLabels(otherLabels); allowed || reason != "label not in allowed list: owner" {
+		t.Errorf("Expected network label owner to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+
+	if allowed, _ := (&AdvancedFilter{}).CheckVolumeLabels(otherLabels); !allowed {
+		t.Error("Expected labels to be allowed without volume filters")
+	}
+}
@@ -0,0 +1,145 @@
+package filters
+
+import (
+	"path"
+	"strings"
+)
+
+// containerMount is a host path or named volume used by a container
+type containerMount struct {
+	volumeName string // Named volume (empty for bind mounts and anonymous volumes)
+	hostPath   string // Bind source or volume driver "device" option
+	driver     string // Volume driver
+}
+
+// checkContainerMounts evaluates every mount of a container create request
+// against the volume filter: HostConfig.Binds, HostConfig.Mounts and the
+// options of the volume drivers they use
+func (af *AdvancedFilter) checkContainerMounts(config map[string]interface{}) (bool, string) {
+	if af.Volumes == nil {
+		return true, ""
+	}
+
+	for _, mount := range containerMounts(config) {
+		if ok, msg := af.Volumes.checkMount(mount); !ok {
+			return false, msg
+		}
+	}
+	return true, ""
+}
+
+// checkMount applies the name, path and driver rules to one container mount
+func (vf *VolumeFilter) checkMount(mount containerMount) (bool, string) {
+	if mount.volumeName != "" {
+		if ok, msg := checkDeniedList(vf.DeniedNames, mount.volumeName, "volume name is denied"); !ok {
+			return false, msg
+		}
+		if ok, msg := checkAllowedList(vf.AllowedNames, mount.volumeName, "volume name not in allowed list"); !ok {
+			return false, msg
+		}
+	}
+
+	if mount.hostPath != "" {
+		if ok, msg := checkDeniedList(vf.DeniedPaths, mount.hostPath, "host path is denied"); !ok {
+			return false, msg
+		}
+		if ok, msg := checkAllowedList(vf.AllowedPaths, mount.hostPath, "host path not in allowed list"); !ok {
+			return false, msg
+		}
+	}
+
+	if mount.driver != "" && len(vf.AllowedDrivers) > 0 && !contains(vf.AllowedDrivers, mount.driver) {
+		return false, "volume driver not allowed: " + mount.driver
+	}
+
+	return true, ""
+}
+
+// containerMounts lists the mounts of a container create request
+func containerMounts(config map[string]interface{}) []containerMount {
+	hostConfig, ok := config["HostConfig"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var mounts []containerMount
+	volumeDriver, _ := hostConfig["VolumeDriver"].(string)
+
+	// Binds: "source:target[:options]", the source is a host path or a volume name
+	if binds, ok := hostConfig["Binds"].([]interface{}); ok {
+		for _, bind := range binds {
+			spec, ok := bind.(string)
+			if !ok {
+				continue
+			}
+			source, _, _ := strings.Cut(spec, ":")
+			if strings.HasPrefix(source, "/") {
+				mounts = append(mounts, containerMount{hostPath: cleanHostPath(source)})
+			} else if source != "" {
+				mounts = append(mounts, containerMount{volumeName: source, driver: volumeDriver})
+			}
+		}
+	}
+
+	// Mounts: bind sources are host paths, volumes may carry driver options
+	if entries, ok := hostConfig["Mounts"].([]interface{}); ok {
+		for _, entry := range entries {
+			m, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			mountType, _ := m["Type"].(string)
+			source, _ := m["Source"].(string)
+
+			switch mountType {
+			case "bind":
+				mounts = append(mounts, containerMount{hostPath: cleanHostPath(source)})
+			case "volume", "":
+				mount := containerMount{volumeName: source, driver: volumeDriver}
+				if options, ok := m["VolumeOptions"].(map[string]interface{}); ok {
+					if driverConfig, ok := options["DriverConfig"].(map[string]interface{}); ok {
+						if name, ok := driverConfig["Name"].(string); ok && name != "" {
+							mount.driver = name
+						}
+						if driverOpts, ok := driverConfig["Options"].(map[string]interface{}); ok {
+							mount.hostPath = DriverOptsHostPath(driverOpts)
+						}
+					}
+				}
+				mounts = append(mounts, mount)
+			}
+		}
+	}
+
+	return mounts
+}
+
+// DriverOptsHostPath returns the host path used by volume driver options,
+// such as the local driver with "type=none,o=bind,device=/path", or ""
+func DriverOptsHostPath(driverOpts map[string]interface{}) string {
+	hostPath := ""
+	if device, ok := driverOpts["device"].(string); ok {
+		hostPath = device
+	}
+	if o, ok := driverOpts["o"].(string); ok {
+		for _, option := range strings.Split(o, ",") {
+			if device, found := strings.CutPrefix(strings.TrimSpace(option), "device="); found {
+				hostPath = device
+			}
+		}
+	}
+	if !strings.HasPrefix(hostPath, "/") {
+		// Not a local path (NFS address, block device name...): keep it as is
+		return hostPath
+	}
+	return cleanHostPath(hostPath)
+}
+
+// cleanHostPath normalizes a host path so that "//var/run/./docker.sock" or a
+// trailing slash cannot bypass path patterns
+func cleanHostPath(hostPath string) string {
+	if hostPath == "" {
+		return ""
+	}
+	return path.Clean(hostPath)
+}
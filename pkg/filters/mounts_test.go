@@ -0,0 +1,131 @@
+package filters
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// containerConfig decodes a container create body the way the middleware does
+func containerConfig(t *testing.T, body string) map[string]interface{} {
+	t.Helper()
+
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+
+	var config map[string]interface{}
+	if err := decoder.Decode(&config); err != nil {
+		t.Fatalf("Invalid test body: %v", err)
+	}
+	return config
+}
+
+func TestCheckContainerCreateMounts(t *testing.T) {
+	filter := &AdvancedFilter{
+		Volumes: &VolumeFilter{
+			DeniedPaths:    []string{`^/var/run/docker\.sock$`, `^/etc`},
+			DeniedNames:    []string{`^secrets$`},
+			AllowedDrivers: []string{"local"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{
+			name:          "No mounts",
+			body:          `{"Image":"nginx"}`,
+			expectAllowed: true,
+		},
+		{
+			name:          "Docker socket bind",
+			body:          `{"Image":"nginx","HostConfig":{"Binds":["/var/run/docker.sock:/var/run/docker.sock"]}}`,
+			expectAllowed: false,
+			expectReason:  "host path is denied: /var/run/docker.sock",
+		},
+		{
+			name:          "Bind path normalized",
+			body:          `{"Image":"nginx","HostConfig":{"Binds":["//var/run/./docker.sock:/sock:ro"]}}`,
+			expectAllowed: false,
+			expectReason:  "host path is denied: /var/run/docker.sock",
+		},
+		{
+			name:          "Allowed bind",
+			body:          `{"Image":"nginx","HostConfig":{"Binds":["/srv/www:/usr/share/nginx/html:ro"]}}`,
+			expectAllowed: true,
+		},
+		{
+			name:          "Denied named volume in Binds",
+			body:          `{"Image":"nginx","HostConfig":{"Binds":["secrets:/run/secrets"]}}`,
+			expectAllowed: false,
+			expectReason:  "volume name is denied: secrets",
+		},
+		{
+			name:          "Volume driver of Binds",
+			body:          `{"Image":"nginx","HostConfig":{"Binds":["data:/data"],"VolumeDriver":"sshfs"}}`,
+			expectAllowed: false,
+			expectReason:  "volume driver not allowed: sshfs",
+		},
+		{
+			name:          "Bind mount in Mounts",
+			body:          `{"Image":"nginx","HostConfig":{"Mounts":[{"Type":"bind","Source":"/etc/","Target":"/host-etc"}]}}`,
+			expectAllowed: false,
+			expectReason:  "host path is denied: /etc",
+		},
+		{
+			name: "Volume driver options in Mounts",
+			body: `{"Image":"nginx","HostConfig":{"Mounts":[{"Type":"volume","Source":"sock","Target":"/sock",
+				"VolumeOptions":{"DriverConfig":{"Name":"local","Options":{"type":"none","o":"bind,device=/var/run/docker.sock"}}}}]}}`,
+			expectAllowed: false,
+			expectReason:  "host path is denied: /var/run/docker.sock",
+		},
+		{
+			name: "Volume driver in Mounts",
+			body: `{"Image":"nginx","HostConfig":{"Mounts":[{"Type":"volume","Source":"data","Target":"/data",
+				"VolumeOptions":{"DriverConfig":{"Name":"rexray"}}}]}}`,
+			expectAllowed: false,
+			expectReason:  "volume driver not allowed: rexray",
+		},
+		{
+			name:          "Anonymous volume and tmpfs",
+			body:          `{"Image":"nginx","HostConfig":{"Mounts":[{"Type":"volume","Target":"/cache"},{"Type":"tmpfs","Target":"/tmp"}]}}`,
+			expectAllowed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", containerConfig(t, tt.body))
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestDriverOptsHostPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		driverOpts map[string]interface{}
+		expected   string
+	}{
+		{"Device option", map[string]interface{}{"device": "/data/"}, "/data"},
+		{"Device in mount options", map[string]interface{}{"type": "none", "o": "bind,device=/var/lib/../run/docker.sock"}, "/var/run/docker.sock"},
+		{"Remote device kept as is", map[string]interface{}{"type": "nfs", "device": ":/exports/data"}, ":/exports/data"},
+		{"No device", map[string]interface{}{"type": "tmpfs"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := DriverOptsHostPath(tt.driverOpts); result != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, result)
+			}
+		})
+	}
+}
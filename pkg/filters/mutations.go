@@ -0,0 +1,175 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MutationFilter declares the changes made to creation requests before they
+// are checked and forwarded to the daemon
+type MutationFilter struct {
+	Containers *ContainerMutation `json:"containers,omitempty"` // POST /containers/create
+	Volumes    *LabelMutation     `json:"volumes,omitempty"`    // POST /volumes/create
+	Networks   *LabelMutation     `json:"networks,omitempty"`   // POST /networks/create
+}
+
+// ContainerMutation defines the settings forced or defaulted on container creations
+type ContainerMutation struct {
+	Labels           map[string]string `json:"labels,omitempty"`             // Labels set, replacing the requested value
+	SecurityOpt      []string          `json:"security_opt,omitempty"`       // Security options added, replacing one with the same key, e.g. "no-new-privileges"
+	DefaultMemory    string            `json:"default_memory,omitempty"`     // Memory limit set when the request has none, e.g. "512m"
+	DefaultPidsLimit int64             `json:"default_pids_limit,omitempty"` // PIDs limit set when the request has none
+	ReadonlyRootfs   bool              `json:"readonly_rootfs,omitempty"`    // Force --read-only
+	Init             bool              `json:"init,omitempty"`               // Force --init
+}
+
+// LabelMutation defines the labels set on volume or network creations
+type LabelMutation struct {
+	Labels map[string]string `json:"labels,omitempty"` // Labels set, replacing the requested value
+}
+
+// MutateContainerCreate applies the container mutations to a creation body and
+// returns the applied patches. Settings the request already has are not reported.
+func (af *AdvancedFilter) MutateContainerCreate(config map[string]interface{}) ([]string, error) {
+	if af.Mutations == nil || af.Mutations.Containers == nil {
+		return nil, nil
+	}
+
+	cm := af.Mutations.Containers
+	patches := setLabels(config, cm.Labels)
+
+	hostConfig, _ := config["HostConfig"].(map[string]interface{})
+	if hostConfig == nil {
+		hostConfig = map[string]interface{}{}
+	}
+
+	patches = append(patches, addSecurityOptions(hostConfig, cm.SecurityOpt)...)
+
+	if cm.DefaultMemory != "" {
+		memory, err := parseMemorySize(cm.DefaultMemory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default_memory in filter configuration: %s", cm.DefaultMemory)
+		}
+		if current := numberField(hostConfig, "Memory"); current <= 0 {
+			hostConfig["Memory"] = json.Number(strconv.FormatInt(memory, 10))
+			patches = append(patches, "set HostConfig.Memory="+strconv.FormatInt(memory, 10))
+		}
+	}
+
+	// 0 and -1 both mean no PIDs limit
+	if cm.DefaultPidsLimit > 0 {
+		if current := numberField(hostConfig, "PidsLimit"); current <= 0 {
+			hostConfig["PidsLimit"] = json.Number(strconv.FormatInt(cm.DefaultPidsLimit, 10))
+			patches = append(patches, "set HostConfig.PidsLimit="+strconv.FormatInt(cm.DefaultPidsLimit, 10))
+		}
+	}
+
+	if cm.ReadonlyRootfs {
+		if readonly, _ := hostConfig["ReadonlyRootfs"].(bool); !readonly {
+			hostConfig["ReadonlyRootfs"] = true
+			patches = append(patches, "set HostConfig.ReadonlyRootfs=true")
+		}
+	}
+
+	if cm.Init {
+		if enabled, _ := hostConfig["Init"].(bool); !enabled {
+			hostConfig["Init"] = true
+			patches = append(patches, "set HostConfig.Init=true")
+		}
+	}
+
+	if len(hostConfig) > 0 {
+		config["HostConfig"] = hostConfig
+	}
+	return patches, nil
+}
+
+// MutateVolumeCreate applies the volume mutations to a creation body and returns the applied patches
+func (af *AdvancedFilter) MutateVolumeCreate(config map[string]interface{}) []string {
+	if af.Mutations == nil || af.Mutations.Volumes == nil {
+		return nil
+	}
+	return setLabels(config, af.Mutations.Volumes.Labels)
+}
+
+// MutateNetworkCreate applies the network mutations to a creation body and returns the applied patches
+func (af *AdvancedFilter) MutateNetworkCreate(config map[string]interface{}) []string {
+	if af.Mutations == nil || af.Mutations.Networks == nil {
+		return nil
+	}
+	return setLabels(config, af.Mutations.Networks.Labels)
+}
+
+// setLabels sets the labels of a creation body (top-level Labels for containers,
+// volumes and networks alike)
+func setLabels(config map[string]interface{}, labels map[string]string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	current, _ := config["Labels"].(map[string]interface{})
+	if current == nil {
+		current = map[string]interface{}{}
+	}
+
+	var patches []string
+	for _, key := range sortedKeys(labels) {
+		if value, ok := current[key].(string); ok && value == labels[key] {
+			continue
+		}
+		current[key] = labels[key]
+		patches = append(patches, "set Labels."+key+"="+labels[key])
+	}
+
+	config["Labels"] = current
+	return patches
+}
+
+// addSecurityOptions adds security options to HostConfig.SecurityOpt, replacing
+// the options with the same key. SELinux labels are keyed by their own field
+// (label=type:..., label=level:...).
+func addSecurityOptions(hostConfig map[string]interface{}, options []string) []string {
+	if len(options) == 0 {
+		return nil
+	}
+
+	current, _ := hostConfig["SecurityOpt"].([]interface{})
+
+	var patches []string
+	for _, raw := range options {
+		option := parseSecurityOption(raw)
+		slot := securityOptionSlot(option)
+
+		updated := make([]interface{}, 0, len(current)+1)
+		replaced := false
+		for _, item := range current {
+			existing, _ := item.(string)
+			if securityOptionSlot(parseSecurityOption(existing)) != slot {
+				updated = append(updated, item)
+				continue
+			}
+			if existing != option.raw {
+				patches = append(patches, "replace HostConfig.SecurityOpt "+existing+" with "+option.raw)
+			}
+			replaced = true
+		}
+		if !replaced {
+			patches = append(patches, "add HostConfig.SecurityOpt "+option.raw)
+		}
+		current = append(updated, option.raw)
+	}
+
+	hostConfig["SecurityOpt"] = current
+	return patches
+}
+
+// securityOptionSlot identifies the setting a security option controls
+func securityOptionSlot(option securityOption) string {
+	if option.key == "label" {
+		field, _, _ := strings.Cut(option.value, ":")
+		return "label:" + field
+	}
+	return option.key
+}
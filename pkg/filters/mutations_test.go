@@ -0,0 +1,132 @@
+package filters
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMutateContainerCreate(t *testing.T) {
+	filter := &AdvancedFilter{
+		Mutations: &MutationFilter{
+			Containers: &ContainerMutation{
+				Labels:           map[string]string{"com.example.owner": "ci"},
+				SecurityOpt:      []string{"no-new-privileges", "label=type:svirt_apache_t"},
+				DefaultMemory:    "512m",
+				DefaultPidsLimit: 256,
+				ReadonlyRootfs:   true,
+				Init:             true,
+			},
+		},
+	}
+
+	t.Run("Empty request", func(t *testing.T) {
+		config := map[string]interface{}{"Image": "nginx"}
+		patches, err := filter.MutateContainerCreate(config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []string{
+			"set Labels.com.example.owner=ci",
+			"add HostConfig.SecurityOpt no-new-privileges",
+			"add HostConfig.SecurityOpt label=type:svirt_apache_t",
+			"set HostConfig.Memory=536870912",
+			"set HostConfig.PidsLimit=256",
+			"set HostConfig.ReadonlyRootfs=true",
+			"set HostConfig.Init=true",
+		}
+		if !reflect.DeepEqual(patches, expected) {
+			t.Errorf("Expected patches %v, got %v", expected, patches)
+		}
+
+		hostConfig := config["HostConfig"].(map[string]interface{})
+		if hostConfig["Memory"] != json.Number("536870912") || hostConfig["ReadonlyRootfs"] != true || hostConfig["Init"] != true {
+			t.Errorf("Unexpected host config: %v", hostConfig)
+		}
+		if config["Labels"].(map[string]interface{})["com.example.owner"] != "ci" {
+			t.Errorf("Unexpected labels: %v", config["Labels"])
+		}
+	})
+
+	t.Run("Request already compliant", func(t *testing.T) {
+		config := map[string]interface{}{
+			"Labels": map[string]interface{}{"com.example.owner": "ci", "app": "web"},
+			"HostConfig": map[string]interface{}{
+				"SecurityOpt":    []interface{}{"no-new-privileges", "label=type:svirt_apache_t", "seccomp=default.json"},
+				"Memory":         float64(1 << 30),
+				"PidsLimit":      float64(100),
+				"ReadonlyRootfs": true,
+				"Init":           true,
+			},
+		}
+		patches, err := filter.MutateContainerCreate(config)
+		if err != nil || len(patches) != 0 {
+			t.Errorf("Expected no patch, got %v (%v)", patches, err)
+		}
+		if config["HostConfig"].(map[string]interface{})["Memory"] != float64(1<<30) {
+			t.Error("Expected the requested memory limit to be kept")
+		}
+	})
+
+	t.Run("Requested values are replaced", func(t *testing.T) {
+		config := map[string]interface{}{
+			"Labels": map[string]interface{}{"com.example.owner": "prod"},
+			"HostConfig": map[string]interface{}{
+				"SecurityOpt": []interface{}{"no-new-privileges=false", "label=level:s0:c100", "label=type:spc_t"},
+				"PidsLimit":   float64(-1),
+			},
+		}
+		patches, err := filter.MutateContainerCreate(config)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []string{
+			"set Labels.com.example.owner=ci",
+			"replace HostConfig.SecurityOpt no-new-privileges=false with no-new-privileges",
+			"replace HostConfig.SecurityOpt label=type:spc_t with label=type:svirt_apache_t",
+			"set HostConfig.Memory=536870912",
+			"set HostConfig.PidsLimit=256",
+			"set HostConfig.ReadonlyRootfs=true",
+			"set HostConfig.Init=true",
+		}
+		if !reflect.DeepEqual(patches, expected) {
+			t.Errorf("Expected patches %v, got %v", expected, patches)
+		}
+
+		securityOpt := config["HostConfig"].(map[string]interface{})["SecurityOpt"]
+		expectedOpt := []interface{}{"label=level:s0:c100", "no-new-privileges", "label=type:svirt_apache_t"}
+		if !reflect.DeepEqual(securityOpt, expectedOpt) {
+			t.Errorf("Expected security options %v, got %v", expectedOpt, securityOpt)
+		}
+	})
+
+	t.Run("Invalid default memory", func(t *testing.T) {
+		invalid := &AdvancedFilter{Mutations: &MutationFilter{Containers: &ContainerMutation{DefaultMemory: "lots"}}}
+		if _, err := invalid.MutateContainerCreate(map[string]interface{}{}); err == nil {
+			t.Error("Expected an error for an invalid default memory")
+		}
+	})
+}
+
+func TestMutateVolumeAndNetworkCreate(t *testing.T) {
+	filter := &AdvancedFilter{
+		Mutations: &MutationFilter{
+			Volumes: &LabelMutation{Labels: map[string]string{"com.example.owner": "ci"}},
+		},
+	}
+
+	volume := map[string]interface{}{"Name": "data"}
+	if patches := filter.MutateVolumeCreate(volume); len(patches) != 1 || patches[0] != "set Labels.com.example.owner=ci" {
+		t.Errorf("Unexpected volume patches: %v", patches)
+	}
+	if volume["Labels"].(map[string]interface{})["com.example.owner"] != "ci" {
+		t.Errorf("Unexpected volume labels: %v", volume["Labels"])
+	}
+
+	network := map[string]interface{}{"Name": "app"}
+	if patches := filter.MutateNetworkCreate(network); len(patches) != 0 || network["Labels"] != nil {
+		t.Errorf("Expected the network to be left unchanged, got %v", network)
+	}
+}
@@ -0,0 +1,92 @@
+package filters
+
+import (
+	"strings"
+)
+
+// containerNamespacePrefix marks a namespace shared with another container ("container:<name|id>")
+const containerNamespacePrefix = "container:"
+
+// hostNamespaceRules maps the HostConfig namespace modes to their deny switch
+var hostNamespaceRules = []struct {
+	field string
+	label string
+	deny  func(cf *ContainerFilter) bool
+}{
+	{"PidMode", "PID", func(cf *ContainerFilter) bool { return cf.DenyHostPID }},
+	{"IpcMode", "IPC", func(cf *ContainerFilter) bool { return cf.DenyHostIPC }},
+	{"UTSMode", "UTS", func(cf *ContainerFilter) bool { return cf.DenyHostUTS }},
+	{"UsernsMode", "user", func(cf *ContainerFilter) bool { return cf.DenyHostUserns }},
+	{"CgroupnsMode", "cgroup", func(cf *ContainerFilter) bool { return cf.DenyHostCgroupns }},
+}
+
+// containerNamespaceFields are the modes that can join another container's namespace
+var containerNamespaceFields = []string{"NetworkMode", "PidMode", "IpcMode"}
+
+// checkNamespaces validates the namespaces shared with the host or with other containers
+func checkNamespaces(cf *ContainerFilter, config map[string]interface{}) (bool, string) {
+	hostConfig, ok := config["HostConfig"].(map[string]interface{})
+	if !ok {
+		return true, ""
+	}
+
+	for _, rule := range hostNamespaceRules {
+		if mode, _ := hostConfig[rule.field].(string); mode == "host" && rule.deny(cf) {
+			return false, "host " + rule.label + " namespace is denied"
+		}
+	}
+
+	for _, field := range containerNamespaceFields {
+		mode, _ := hostConfig[field].(string)
+		target, found := strings.CutPrefix(mode, containerNamespacePrefix)
+		if !found {
+			continue
+		}
+		if cf.DenyContainerNamespaces {
+			return false, "joining another container's namespace is denied: " + field + "=" + mode
+		}
+		if isProtectedContainer(cf.ProtectedContainers, target) {
+			return false, "joining a protected container's namespace is denied: " + field + "=" + mode
+		}
+	}
+
+	return true, ""
+}
+
+// isProtectedContainer reports whether a container reference (name, ID or ID
+// prefix, as accepted by Docker) designates one of the protected containers
+func isProtectedContainer(protected []string, reference string) bool {
+	reference = strings.TrimPrefix(strings.TrimSpace(reference), "/")
+	if reference == "" {
+		return false
+	}
+
+	for _, container := range protected {
+		container = strings.TrimPrefix(container, "/")
+		if container == "" {
+			continue
+		}
+		if reference == container {
+			return true
+		}
+		// Docker resolves any unique ID prefix
+		if isHexID(reference) && isHexID(container) &&
+			(strings.HasPrefix(container, reference) || strings.HasPrefix(reference, container)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHexID reports whether the value looks like a (possibly truncated) container ID
+func isHexID(value string) bool {
+	if value == "" || len(value) > 64 {
+		return false
+	}
+	for _, r := range value {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
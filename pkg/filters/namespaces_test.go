@@ -0,0 +1,67 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreateNamespaces(t *testing.T) {
+	const proxyID = "3f4e1b2c9d8a7f6e5d4c3b2a1908f7e6d5c4b3a29180f7e6d5c4b3a291807f6e"
+
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			DenyHostPID:         true,
+			DenyHostIPC:         true,
+			DenyHostUTS:         true,
+			DenyHostUserns:      true,
+			DenyHostCgroupns:    true,
+			ProtectedContainers: []string{"dockershield", proxyID},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		hostConfig    string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Private namespaces", `{"PidMode":"","IpcMode":"private","CgroupnsMode":"private"}`, true, ""},
+		{"Host PID", `{"PidMode":"host"}`, false, "host PID namespace is denied"},
+		{"Host IPC", `{"IpcMode":"host"}`, false, "host IPC namespace is denied"},
+		{"Host UTS", `{"UTSMode":"host"}`, false, "host UTS namespace is denied"},
+		{"Host user namespace", `{"UsernsMode":"host"}`, false, "host user namespace is denied"},
+		{"Host cgroup namespace", `{"CgroupnsMode":"host"}`, false, "host cgroup namespace is denied"},
+		{"Join another container", `{"NetworkMode":"container:web"}`, true, ""},
+		{"Join proxy by name", `{"PidMode":"container:dockershield"}`, false, "joining a protected container's namespace is denied: PidMode=container:dockershield"},
+		{"Join proxy by slash name", `{"NetworkMode":"container:/dockershield"}`, false, "joining a protected container's namespace is denied: NetworkMode=container:/dockershield"},
+		{"Join proxy by short ID", `{"IpcMode":"container:3f4e1b2c9d8a"}`, false, "joining a protected container's namespace is denied: IpcMode=container:3f4e1b2c9d8a"},
+		{"Join other container by ID", `{"IpcMode":"container:9a8b7c6d5e4f"}`, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := containerConfig(t, `{"Image":"nginx","HostConfig":`+tt.hostConfig+`}`)
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", config)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckContainerCreateDenyContainerNamespaces(t *testing.T) {
+	filter := &AdvancedFilter{Containers: &ContainerFilter{DenyContainerNamespaces: true}}
+
+	for _, field := range []string{"NetworkMode", "PidMode", "IpcMode"} {
+		config := containerConfig(t, `{"Image":"nginx","HostConfig":{"`+field+`":"container:web"}}`)
+		if allowed, _ := filter.CheckContainerCreate("nginx", "app", config); allowed {
+			t.Errorf("Expected %s=container:web to be denied", field)
+		}
+	}
+
+	// Host modes are controlled by their own switches
+	config := containerConfig(t, `{"Image":"nginx","HostConfig":{"PidMode":"host"}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "app", config); !allowed {
+		t.Errorf("Expected PidMode=host to be allowed, got: %s", reason)
+	}
+}
@@ -0,0 +1,68 @@
+package filters
+
+import (
+	"strings"
+)
+
+// RenameFilter defines the names a container may be renamed to
+// (POST /containers/{id}/rename, i.e. docker rename)
+type RenameFilter struct {
+	DenyAll      bool     `json:"deny_all,omitempty"`      // Deny any rename
+	AllowedNames []string `json:"allowed_names,omitempty"` // Allowed new names (patterns)
+	DeniedNames  []string `json:"denied_names,omitempty"`  // Denied new names (patterns)
+}
+
+// CommitFilter defines the images that may be created from a container
+// (POST /commit, i.e. docker commit)
+type CommitFilter struct {
+	DenyAll       bool     `json:"deny_all,omitempty"`       // Deny any commit
+	DeniedChanges []string `json:"denied_changes,omitempty"` // Denied Dockerfile instructions of --change (patterns), e.g. "^USER root"
+}
+
+// CheckContainerRename checks if a container may be renamed.
+// The new name must also satisfy the container name rules.
+func (af *AdvancedFilter) CheckContainerRename(name string) (bool, string) {
+	if rf := af.Rename; rf != nil {
+		if rf.DenyAll {
+			return false, "container rename is denied"
+		}
+		if ok, msg := checkDeniedList(rf.DeniedNames, name, "container name is denied"); !ok {
+			return false, msg
+		}
+		if ok, msg := checkAllowedList(rf.AllowedNames, name, "container name not in allowed list"); !ok {
+			return false, msg
+		}
+	}
+
+	if cf := af.Containers; cf != nil {
+		if ok, msg := checkDeniedList(cf.DeniedNames, name, "container name is denied"); !ok {
+			return false, msg
+		}
+		if ok, msg := checkAllowedList(cf.AllowedNames, name, "container name not in allowed list"); !ok {
+			return false, msg
+		}
+	}
+
+	return true, ""
+}
+
+// CheckCommit checks if an image may be committed from a container.
+// The image name must also satisfy the image rules.
+func (af *AdvancedFilter) CheckCommit(image string, changes []string) (bool, string) {
+	if cf := af.Commit; cf != nil {
+		if cf.DenyAll {
+			return false, "container commit is denied"
+		}
+		for _, change := range changes {
+			change = strings.TrimSpace(change)
+			if ok, msg := checkDeniedList(cf.DeniedChanges, change, "commit change is denied"); !ok {
+				return false, msg
+			}
+		}
+	}
+
+	if image != "" {
+		return af.CheckImageOperation(image)
+	}
+	return true, ""
+}
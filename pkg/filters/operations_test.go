@@ -0,0 +1,171 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerUpdate(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			Resources: &ResourceFilter{MaxMemory: "1g", MaxCPUs: 2, MaxPidsLimit: 256},
+		},
+		RestartPolicy: &RestartPolicyFilter{AllowedPolicies: []string{"no", "on-failure"}, MaxRetries: 5},
+	}
+
+	tests := []struct {
+		name          string
+		update        map[string]interface{}
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Empty update", map[string]interface{}{}, true, ""},
+		{"Limits within maximum", map[string]interface{}{"Memory": float64(512 << 20), "NanoCpus": float64(1e9), "PidsLimit": float64(128)}, true, ""},
+		{"Memory above maximum", map[string]interface{}{"Memory": float64(4 << 30)}, false, "memory limit exceeds maximum: 4294967296 > 1g"},
+		{"CPUs above maximum", map[string]interface{}{"CpuQuota": float64(400000), "CpuPeriod": float64(100000)}, false, "CPU limit exceeds maximum: 4 > 2"},
+		{"CPU quota removed", map[string]interface{}{"CpuQuota": float64(-1)}, false, "CPU limit is required"},
+		{"PIDs limit removed", map[string]interface{}{"PidsLimit": float64(-1)}, false, "PIDs limit is required"},
+		{"Allowed restart policy", map[string]interface{}{"RestartPolicy": map[string]interface{}{"Name": "on-failure", "MaximumRetryCount": float64(3)}}, true, ""},
+		{"Denied restart policy", map[string]interface{}{"RestartPolicy": map[string]interface{}{"Name": "always"}}, false, "restart policy not allowed: always"},
+		{"Too many retries", map[string]interface{}{"RestartPolicy": map[string]interface{}{"Name": "on-failure", "MaximumRetryCount": float64(10)}}, false, "restart retry count exceeds maximum: 10 > 5"},
+		{"Unlimited retries", map[string]interface{}{"RestartPolicy": map[string]interface{}{"Name": "on-failure"}}, false, "restart retry count is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckContainerUpdate(tt.update)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+
+	t.Run("Update resources replace container resources", func(t *testing.T) {
+		updates := &AdvancedFilter{
+			Containers: &ContainerFilter{Resources: &ResourceFilter{MaxMemory: "1g"}},
+			Updates:    &UpdateFilter{Resources: &ResourceFilter{MaxMemory: "4g"}},
+		}
+		if allowed, reason := updates.CheckContainerUpdate(map[string]interface{}{"Memory": float64(2 << 30)}); !allowed {
+			t.Errorf("Expected update maximum to apply, got: %s", reason)
+		}
+	})
+
+	t.Run("Deny all updates", func(t *testing.T) {
+		denyAll := &AdvancedFilter{Updates: &UpdateFilter{DenyAll: true}}
+		if allowed, _ := denyAll.CheckContainerUpdate(map[string]interface{}{}); allowed {
+			t.Error("Expected updates to be denied")
+		}
+	})
+}
+
+func TestCheckContainerCreateRestartPolicy(t *testing.T) {
+	// Restart policy rules apply even without container filters
+	filter := &AdvancedFilter{RestartPolicy: &RestartPolicyFilter{AllowedPolicies: []string{"no", "unless-stopped"}}}
+
+	config := containerConfig(t, `{"Image":"nginx","HostConfig":{"RestartPolicy":{"Name":"unless-stopped"}}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+		t.Errorf("Expected unless-stopped to be allowed, got: %s", reason)
+	}
+
+	config = containerConfig(t, `{"Image":"nginx","HostConfig":{"RestartPolicy":{"Name":""}}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+		t.Errorf("Expected an empty policy to count as 'no', got: %s", reason)
+	}
+
+	config = containerConfig(t, `{"Image":"nginx","HostConfig":{"RestartPolicy":{"Name":"always"}}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); allowed || reason != "restart policy not allowed: always" {
+		t.Errorf("Expected always to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+}
+
+func TestCheckExecCreate(t *testing.T) {
+	filter := &AdvancedFilter{Exec: &ExecFilter{DenyPrivileged: true, DenyRoot: true}}
+
+	tests := []struct {
+		name          string
+		config        map[string]interface{}
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Container user", map[string]interface{}{"Cmd": []interface{}{"ls"}}, true, ""},
+		{"Other user", map[string]interface{}{"Cmd": []interface{}{"ls"}, "User": "1000"}, true, ""},
+		{"Privileged", map[string]interface{}{"Cmd": []interface{}{"sh"}, "Privileged": true}, false, "privileged exec is denied"},
+		{"Root user", map[string]interface{}{"Cmd": []interface{}{"sh"}, "User": "root"}, false, "exec user is root: root"},
+		{"Root UID", map[string]interface{}{"Cmd": []interface{}{"sh"}, "User": "0:0"}, false, "exec user is root: 0:0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckExecCreate(nil, tt.config)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckContainerRename(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{DeniedNames: []string{"^dockershield$"}},
+		Rename:     &RenameFilter{AllowedNames: []string{"^app-"}},
+	}
+
+	if allowed, reason := filter.CheckContainerRename("app-web"); !allowed {
+		t.Errorf("Expected app-web to be allowed, got: %s", reason)
+	}
+	if allowed, reason := filter.CheckContainerRename("web"); allowed || reason != "container name not in allowed list: web" {
+		t.Errorf("Expected web to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+
+	// Container name rules apply without a rename section
+	containersOnly := &AdvancedFilter{Containers: &ContainerFilter{DeniedNames: []string{"^dockershield$"}}}
+	if allowed, reason := containersOnly.CheckContainerRename("dockershield"); allowed || reason != "container name is denied: dockershield" {
+		t.Errorf("Expected dockershield to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+
+	denyAll := &AdvancedFilter{Rename: &RenameFilter{DenyAll: true}}
+	if allowed, _ := denyAll.CheckContainerRename("app-web"); allowed {
+		t.Error("Expected renames to be denied")
+	}
+}
+
+func TestCheckCommit(t *testing.T) {
+	filter := &AdvancedFilter{
+		Commit: &CommitFilter{DeniedChanges: []string{"^(?i)USER\\s+(root|0)\\b", "^(?i)ENTRYPOINT"}},
+		Images: &ImageFilter{DeniedTags: []string{"^latest$"}},
+	}
+
+	tests := []struct {
+		name          string
+		image         string
+		changes       []string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Commit without name", "", nil, true, ""},
+		{"Versioned image", "registry.local/app:1.0", []string{"ENV MODE=debug"}, true, ""},
+		{"Root user change", "registry.local/app:1.0", []string{"user root"}, false, "commit change is denied: user root"},
+		{"Entrypoint change", "", []string{"ENTRYPOINT [\"sh\"]"}, false, "commit change is denied: ENTRYPOINT [\"sh\"]"},
+		{"Denied tag", "registry.local/app:latest", nil, false, "image tag is denied: latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckCommit(tt.image, tt.changes)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+
+	denyAll := &AdvancedFilter{Commit: &CommitFilter{DenyAll: true}}
+	if allowed, _ := denyAll.CheckCommit("", nil); allowed {
+		t.Error("Expected commits to be denied")
+	}
+}
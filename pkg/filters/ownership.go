@@ -0,0 +1,137 @@
+package filters
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// OwnershipFilter scopes a caller to the containers, volumes and networks
+// carrying its labels. The objects it creates must carry them, and the
+// operations on existing objects (/containers/{id}/*, /volumes/{name},
+// /networks/{id}) are denied on objects that do not.
+type OwnershipFilter struct {
+	Labels map[string]string `json:"labels,omitempty"` // Labels identifying the caller's objects (exact values)
+}
+
+// OwnershipEnabled reports whether operations on existing objects must be
+// checked against the labels of the target object
+func (af *AdvancedFilter) OwnershipEnabled() bool {
+	return af.Ownership != nil && len(af.Ownership.Labels) > 0
+}
+
+// CheckOwnership checks if an existing object belongs to the caller.
+// kind names the object in the reason ("container", "volume", "network").
+func (af *AdvancedFilter) CheckOwnership(kind string, labels map[string]string) (bool, string) {
+	if !af.OwnershipEnabled() {
+		return true, ""
+	}
+
+	for _, key := range sortedKeys(af.Ownership.Labels) {
+		if value, ok := labels[key]; !ok || value != af.Ownership.Labels[key] {
+			return false, kind + " is not owned by the caller: label " + key + " missing or mismatch"
+		}
+	}
+
+	return true, ""
+}
+
+// CheckPrune checks if a prune request only removes the caller's objects:
+// its filters must select every ownership label, e.g. filters={"label":["owner=ci"]}
+func (af *AdvancedFilter) CheckPrune(kind, query string) (bool, string) {
+	if !af.OwnershipEnabled() {
+		return true, ""
+	}
+
+	// The daemon accepts {"label":["k=v"]} and the older {"label":{"k=v":true}}
+	var pruneFilters map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(query), &pruneFilters)
+
+	selected := map[string]bool{}
+	var list []string
+	if err := json.Unmarshal(pruneFilters["label"], &list); err == nil {
+		for _, label := range list {
+			selected[label] = true
+		}
+	} else {
+		_ = json.Unmarshal(pruneFilters["label"], &selected)
+	}
+
+	for _, key := range sortedKeys(af.Ownership.Labels) {
+		if !selected[key+"="+af.Ownership.Labels[key]] {
+			return false, kind + " prune must be filtered on label " + key
+		}
+	}
+
+	return true, ""
+}
+
+// ObjectReference is an existing object used by a creation request
+type ObjectReference struct {
+	Kind string // "container" or "volume"
+	ID   string // Name or ID, as given in the request
+}
+
+// ContainerCreateReferences lists the containers and named volumes a container
+// creation uses: HostConfig.VolumesFrom, the named volumes of Binds and Mounts,
+// and the container:<id> network, PID and IPC modes. The caller must own them
+// as much as the objects it operates on directly.
+func ContainerCreateReferences(config map[string]interface{}) []ObjectReference {
+	hostConfig, ok := config["HostConfig"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	var refs []ObjectReference
+
+	// VolumesFrom: "container[:ro|rw]"
+	if volumesFrom, ok := hostConfig["VolumesFrom"].([]interface{}); ok {
+		for _, entry := range volumesFrom {
+			spec, _ := entry.(string)
+			if container, _, _ := strings.Cut(spec, ":"); container != "" {
+				refs = append(refs, ObjectReference{Kind: "container", ID: container})
+			}
+		}
+	}
+
+	for _, mount := range containerMounts(config) {
+		if mount.volumeName != "" {
+			refs = append(refs, ObjectReference{Kind: "volume", ID: mount.volumeName})
+		}
+	}
+
+	for _, field := range containerNamespaceFields {
+		mode, _ := hostConfig[field].(string)
+		if container, found := strings.CutPrefix(mode, containerNamespacePrefix); found && container != "" {
+			refs = append(refs, ObjectReference{Kind: "container", ID: container})
+		}
+	}
+
+	return refs
+}
+
+// checkOwnerLabels validates that a creation carries the ownership labels,
+// so that the caller can operate on the object afterwards
+func checkOwnerLabels(of *OwnershipFilter, labels map[string]interface{}) (bool, string) {
+	if of == nil {
+		return true, ""
+	}
+
+	for _, key := range sortedKeys(of.Labels) {
+		if value, ok := labels[key].(string); !ok || value != of.Labels[key] {
+			return false, "ownership label missing or mismatch: " + key
+		}
+	}
+
+	return true, ""
+}
+
+// sortedKeys returns the keys of a label map in a stable order
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
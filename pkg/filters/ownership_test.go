@@ -0,0 +1,124 @@
+package filters
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCheckOwnership(t *testing.T) {
+	filter := &AdvancedFilter{
+		Ownership: &OwnershipFilter{Labels: map[string]string{"com.example.owner": "ci"}},
+	}
+
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Owned", map[string]string{"com.example.owner": "ci", "app": "web"}, true, ""},
+		{"Other owner", map[string]string{"com.example.owner": "prod"}, false, "container is not owned by the caller: label com.example.owner missing or mismatch"},
+		{"No labels", nil, false, "container is not owned by the caller: label com.example.owner missing or mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckOwnership("container", tt.labels)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+
+	t.Run("Without ownership labels", func(t *testing.T) {
+		if allowed, reason := (&AdvancedFilter{}).CheckOwnership("volume", nil); !allowed {
+			t.Errorf("Expected every object to be allowed, got: %s", reason)
+		}
+	})
+}
+
+func TestCheckPrune(t *testing.T) {
+	filter := &AdvancedFilter{
+		Ownership: &OwnershipFilter{Labels: map[string]string{"com.example.owner": "ci"}},
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		expectAllowed bool
+	}{
+		{"Label list", `{"label":["com.example.owner=ci"]}`, true},
+		{"Label map", `{"label":{"com.example.owner=ci":true}}`, true},
+		{"With other filters", `{"label":["com.example.owner=ci"],"until":["24h"]}`, true},
+		{"Unfiltered", ``, false},
+		{"Other owner", `{"label":["com.example.owner=prod"]}`, false},
+		{"Label key only", `{"label":["com.example.owner"]}`, false},
+		{"Disabled label", `{"label":{"com.example.owner=ci":false}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := filter.CheckPrune("volume", tt.query)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+		})
+	}
+}
+
+func TestCreationRequiresOwnershipLabels(t *testing.T) {
+	filter := &AdvancedFilter{
+		Ownership: &OwnershipFilter{Labels: map[string]string{"com.example.owner": "ci"}},
+	}
+	owned := map[string]interface{}{"com.example.owner": "ci"}
+	other := map[string]interface{}{"com.example.owner": "prod"}
+	const reason = "ownership label missing or mismatch: com.example.owner"
+
+	if allowed, msg := filter.CheckContainerCreate("nginx", "web", map[string]interface{}{"Labels": owned}); !allowed {
+		t.Errorf("Expected owned container to be allowed, got: %s", msg)
+	}
+	if _, msg := filter.CheckContainerCreate("nginx", "web", map[string]interface{}{}); msg != reason {
+		t.Errorf("Expected reason '%s', got '%s'", reason, msg)
+	}
+	if allowed, msg := filter.CheckVolumeLabels(owned); !allowed {
+		t.Errorf("Expected owned volume to be allowed, got: %s", msg)
+	}
+	if _, msg := filter.CheckVolumeLabels(other); msg != reason {
+		t.Errorf("Expected reason '%s', got '%s'", reason, msg)
+	}
+	if _, msg := filter.CheckNetworkLabels(nil); msg != reason {
+		t.Errorf("Expected reason '%s', got '%s'", reason, msg)
+	}
+}
+
+func TestContainerCreateReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected []ObjectReference
+	}{
+		{"No HostConfig", `{"Image":"nginx"}`, nil},
+		{"VolumesFrom", `{"HostConfig":{"VolumesFrom":["data:ro","cache"]}}`, []ObjectReference{{"container", "data"}, {"container", "cache"}}},
+		{"Named volume bind", `{"HostConfig":{"Binds":["cache:/cache:rw","/srv/www:/www"]}}`, []ObjectReference{{"volume", "cache"}}},
+		{"Volume mount", `{"HostConfig":{"Mounts":[{"Type":"volume","Source":"db","Target":"/db"},{"Type":"volume","Target":"/tmp"},{"Type":"bind","Source":"/srv","Target":"/srv"}]}}`, []ObjectReference{{"volume", "db"}}},
+		{"Network namespace", `{"HostConfig":{"NetworkMode":"container:web"}}`, []ObjectReference{{"container", "web"}}},
+		{"PID and IPC namespaces", `{"HostConfig":{"PidMode":"container:web","IpcMode":"container:db"}}`, []ObjectReference{{"container", "web"}, {"container", "db"}}},
+		{"Host and bridge modes", `{"HostConfig":{"NetworkMode":"bridge","PidMode":"host","IpcMode":"shareable"}}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.body), &config); err != nil {
+				t.Fatalf("Invalid test body: %v", err)
+			}
+			if refs := ContainerCreateReferences(config); !reflect.DeepEqual(refs, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, refs)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package filters
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// firstUnprivilegedPort is the lowest port an unprivileged process may bind
+const firstUnprivilegedPort = 1024
+
+// PortFilter defines the host ports a container may publish
+// (HostConfig.PortBindings and PublishAllPorts)
+type PortFilter struct {
+	DenyPublishAll      bool     `json:"deny_publish_all,omitempty"`      // Deny -P / PublishAllPorts
+	DenyPrivilegedPorts bool     `json:"deny_privileged_ports,omitempty"` // Deny host ports below 1024
+	AllowedHostPorts    []string `json:"allowed_host_ports,omitempty"`    // Allowed host ports or ranges, e.g. "8080" or "8000-8999"
+	AllowedHostIPs      []string `json:"allowed_host_ips,omitempty"`      // Allowed host IPs or networks, e.g. "127.0.0.1" or "10.0.0.0/8"
+}
+
+// checkPorts validates the ports published by a container
+func checkPorts(pf *PortFilter, config map[string]interface{}) (bool, string) {
+	if pf == nil {
+		return true, ""
+	}
+
+	hostConfig, ok := config["HostConfig"].(map[string]interface{})
+	if !ok {
+		return true, ""
+	}
+
+	// -P publishes every exposed port on 0.0.0.0 with ephemeral host ports,
+	// which the host port and address lists cannot check
+	if publishAll, _ := hostConfig["PublishAllPorts"].(bool); publishAll {
+		if pf.DenyPublishAll || len(pf.AllowedHostPorts) > 0 || len(pf.AllowedHostIPs) > 0 {
+			return false, "publishing all ports is denied"
+		}
+	}
+
+	bindings, _ := hostConfig["PortBindings"].(map[string]interface{})
+
+	// Check the ports in a stable order, so the reason does not change between calls
+	containerPorts := make([]string, 0, len(bindings))
+	for containerPort := range bindings {
+		containerPorts = append(containerPorts, containerPort)
+	}
+	sort.Strings(containerPorts)
+
+	for _, containerPort := range containerPorts {
+		entries, _ := bindings[containerPort].([]interface{})
+		for _, entry := range entries {
+			binding, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			hostIP, _ := binding["HostIp"].(string)
+			hostPort, _ := binding["HostPort"].(string)
+
+			if ok, msg := checkHostIP(pf.AllowedHostIPs, hostIP); !ok {
+				return false, msg
+			}
+			if ok, msg := checkHostPort(pf, containerPort, hostPort); !ok {
+				return false, msg
+			}
+		}
+	}
+
+	return true, ""
+}
+
+// checkHostIP validates the host address of a binding. An empty address
+// publishes on every IPv4 and IPv6 interface, so 0.0.0.0 and :: must both
+// be allowed.
+func checkHostIP(allowed []string, hostIP string) (bool, string) {
+	if len(allowed) == 0 {
+		return true, ""
+	}
+
+	hostIPs := []string{strings.Trim(hostIP, "[]")}
+	if hostIPs[0] == "" {
+		hostIPs = []string{"0.0.0.0", "::"}
+	}
+
+	for _, hostIP := range hostIPs {
+		if !hostIPAllowed(allowed, net.ParseIP(hostIP)) {
+			return false, "host IP not allowed: " + hostIP
+		}
+	}
+	return true, ""
+}
+
+// hostIPAllowed reports whether an address is one of the allowed IPs or networks
+func hostIPAllowed(allowed []string, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHostPort validates the host port, or port range, of a binding.
+// An empty port lets Docker pick an ephemeral one, which cannot be checked
+// against an allow-list.
+func checkHostPort(pf *PortFilter, containerPort, hostPort string) (bool, string) {
+	first, last, ok := parsePortRange(hostPort)
+	if !ok {
+		return false, "invalid host port: " + hostPort
+	}
+
+	if first == 0 {
+		if len(pf.AllowedHostPorts) > 0 {
+			return false, "host port must be set: " + containerPort
+		}
+		return true, ""
+	}
+
+	if pf.DenyPrivilegedPorts && first < firstUnprivilegedPort {
+		return false, "privileged host port is denied: " + hostPort
+	}
+
+	if len(pf.AllowedHostPorts) > 0 && !portRangeAllowed(pf.AllowedHostPorts, first, last) {
+		return false, "host port not in allowed list: " + hostPort
+	}
+
+	return true, ""
+}
+
+// portRangeAllowed reports whether a port range fits in one of the allowed ranges
+func portRangeAllowed(allowed []string, first, last int) bool {
+	for _, entry := range allowed {
+		allowedFirst, allowedLast, ok := parsePortRange(entry)
+		if ok && allowedFirst > 0 && first >= allowedFirst && last <= allowedLast {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePortRange parses "8080" or "8000-8999"; "" returns 0, 0
+func parsePortRange(value string) (int, int, bool) {
+	first, last, ok := parseRange(value, 65535)
+	return int(first), int(last), ok
+}
+
+// parseRange parses a number or a "first-last" range of numbers up to limit; "" returns 0, 0
+func parseRange(value string, limit int64) (int64, int64, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, 0, true
+	}
+
+	start, end, isRange := strings.Cut(value, "-")
+	first, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil || first < 0 || first > limit {
+		return 0, 0, false
+	}
+	if !isRange {
+		return first, first, true
+	}
+
+	last, err := strconv.ParseInt(strings.TrimSpace(end), 10, 64)
+	if err != nil || last < first || last > limit {
+		return 0, 0, false
+	}
+	return first, last, true
+}
@@ -0,0 +1,96 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreatePorts(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			Ports: &PortFilter{
+				DenyPublishAll:      true,
+				DenyPrivilegedPorts: true,
+				AllowedHostPorts:    []string{"8000-8999", "9443"},
+				AllowedHostIPs:      []string{"127.0.0.1", "::1", "10.0.0.0/8"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		hostConfig    string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"No published ports", `{}`, true, ""},
+		{"Loopback port in range", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"8080"}]}}`, true, ""},
+		{"IPv6 loopback", `{"PortBindings":{"443/tcp":[{"HostIp":"::1","HostPort":"9443"}]}}`, true, ""},
+		{"Allowed network", `{"PortBindings":{"53/udp":[{"HostIp":"10.1.2.3","HostPort":"8053"}]}}`, true, ""},
+		{"Port range in range", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"8080-8090"}]}}`, true, ""},
+		{"Publish all", `{"PublishAllPorts":true}`, false, "publishing all ports is denied"},
+		{"All interfaces", `{"PortBindings":{"80/tcp":[{"HostIp":"","HostPort":"8080"}]}}`, false, "host IP not allowed: 0.0.0.0"},
+		{"Public address", `{"PortBindings":{"80/tcp":[{"HostIp":"192.168.1.10","HostPort":"8080"}]}}`, false, "host IP not allowed: 192.168.1.10"},
+		{"Privileged port", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"80"}]}}`, false, "privileged host port is denied: 80"},
+		{"Port out of range", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"9000"}]}}`, false, "host port not in allowed list: 9000"},
+		{"Range overflowing", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"8990-9010"}]}}`, false, "host port not in allowed list: 8990-9010"},
+		{"Random host port", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":""}]}}`, false, "host port must be set: 80/tcp"},
+		{"Invalid host port", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"http"}]}}`, false, "invalid host port: http"},
+		{"Second binding checked", `{"PortBindings":{"80/tcp":[{"HostIp":"127.0.0.1","HostPort":"8080"},{"HostIp":"0.0.0.0","HostPort":"8081"}]}}`, false, "host IP not allowed: 0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := containerConfig(t, `{"Image":"nginx","HostConfig":`+tt.hostConfig+`}`)
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", config)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckContainerCreatePrivilegedPortsOnly(t *testing.T) {
+	filter := &AdvancedFilter{Containers: &ContainerFilter{Ports: &PortFilter{DenyPrivilegedPorts: true}}}
+
+	config := containerConfig(t, `{"Image":"nginx","HostConfig":{"PublishAllPorts":true,"PortBindings":{"80/tcp":[{"HostPort":""}],"443/tcp":[{"HostPort":"8443"}]}}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+		t.Errorf("Expected random and unprivileged ports to be allowed, got: %s", reason)
+	}
+
+	config = containerConfig(t, `{"Image":"nginx","HostConfig":{"PortBindings":{"443/tcp":[{"HostPort":"1000-1100"}]}}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); allowed || reason != "privileged host port is denied: 1000-1100" {
+		t.Errorf("Expected a range starting below 1024 to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+}
+
+func TestCheckContainerCreateEmptyHostIP(t *testing.T) {
+	// An empty HostIp publishes on 0.0.0.0 and ::
+	config := containerConfig(t, `{"Image":"nginx","HostConfig":{"PortBindings":{"80/tcp":[{"HostIp":"","HostPort":"8080"}]}}}`)
+
+	filter := &AdvancedFilter{Containers: &ContainerFilter{Ports: &PortFilter{AllowedHostIPs: []string{"0.0.0.0"}}}}
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); allowed || reason != "host IP not allowed: ::" {
+		t.Errorf("Expected the IPv6 wildcard to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+
+	filter = &AdvancedFilter{Containers: &ContainerFilter{Ports: &PortFilter{AllowedHostIPs: []string{"0.0.0.0", "::"}}}}
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+		t.Errorf("Expected both wildcards to be allowed, got: %s", reason)
+	}
+}
+
+func TestCheckContainerCreatePublishAllWithAllowLists(t *testing.T) {
+	config := containerConfig(t, `{"Image":"nginx","HostConfig":{"PublishAllPorts":true}}`)
+
+	for name, pf := range map[string]*PortFilter{
+		"Allowed host ports": {AllowedHostPorts: []string{"8000-8999"}},
+		"Allowed host IPs":   {AllowedHostIPs: []string{"127.0.0.1"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			filter := &AdvancedFilter{Containers: &ContainerFilter{Ports: pf}}
+			if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); allowed || reason != "publishing all ports is denied" {
+				t.Errorf("Expected -P to be denied, got allowed=%v reason=%s", allowed, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultCPUPeriod is the CFS period used by Docker when CpuQuota is set without CpuPeriod
+const defaultCPUPeriod = 100000
+
+// ResourceFilter defines the resource limits a container must set
+// (HostConfig.Memory, NanoCpus/CpuQuota and PidsLimit).
+// A maximum value also makes the limit mandatory: no limit means unlimited.
+type ResourceFilter struct {
+	RequireMemory    bool    `json:"require_memory,omitempty"`     // Require a memory limit (--memory)
+	MaxMemory        string  `json:"max_memory,omitempty"`         // Maximum memory limit, e.g. "2g" or "512m"
+	RequireCPU       bool    `json:"require_cpu,omitempty"`        // Require a CPU limit (--cpus or --cpu-quota)
+	MaxCPUs          float64 `json:"max_cpus,omitempty"`           // Maximum number of CPUs, e.g. 1.5
+	RequirePidsLimit bool    `json:"require_pids_limit,omitempty"` // Require a PIDs limit (--pids-limit)
+	MaxPidsLimit     int64   `json:"max_pids_limit,omitempty"`     // Maximum PIDs limit
+}
+
+// checkResources validates the resource limits of a container
+func checkResources(rf *ResourceFilter, config map[string]interface{}) (bool, string) {
+	if rf == nil {
+		return true, ""
+	}
+
+	hostConfig, _ := config["HostConfig"].(map[string]interface{})
+
+	memory := numberField(hostConfig, "Memory")
+	if rf.RequireMemory || rf.MaxMemory != "" {
+		if memory <= 0 {
+			return false, "memory limit is required"
+		}
+	}
+	if rf.MaxMemory != "" {
+		maxMemory, err := parseMemorySize(rf.MaxMemory)
+		if err != nil {
+			return false, "invalid max_memory in filter configuration: " + rf.MaxMemory
+		}
+		if memory > float64(maxMemory) {
+			return false, "memory limit exceeds maximum: " + formatNumber(memory) + " > " + rf.MaxMemory
+		}
+	}
+
+	cpus := containerCPUs(hostConfig)
+	if rf.RequireCPU || rf.MaxCPUs > 0 {
+		if cpus <= 0 {
+			return false, "CPU limit is required"
+		}
+	}
+	if rf.MaxCPUs > 0 && cpus > rf.MaxCPUs {
+		return false, "CPU limit exceeds maximum: " + formatNumber(cpus) + " > " + formatNumber(rf.MaxCPUs)
+	}
+
+	// 0 and -1 both mean unlimited
+	pidsLimit := numberField(hostConfig, "PidsLimit")
+	if rf.RequirePidsLimit || rf.MaxPidsLimit > 0 {
+		if pidsLimit <= 0 {
+			return false, "PIDs limit is required"
+		}
+	}
+	if rf.MaxPidsLimit > 0 && pidsLimit > float64(rf.MaxPidsLimit) {
+		return false, "PIDs limit exceeds maximum: " + formatNumber(pidsLimit) + " > " + strconv.FormatInt(rf.MaxPidsLimit, 10)
+	}
+
+	return true, ""
+}
+
+// containerCPUs returns the number of CPUs a container may use, from NanoCpus
+// (--cpus) or CpuQuota/CpuPeriod (--cpu-quota), or 0 when unlimited
+func containerCPUs(hostConfig map[string]interface{}) float64 {
+	if nanoCPUs := numberField(hostConfig, "NanoCpus"); nanoCPUs > 0 {
+		return nanoCPUs / 1e9
+	}
+
+	quota := numberField(hostConfig, "CpuQuota")
+	if quota <= 0 {
+		return 0
+	}
+	period := numberField(hostConfig, "CpuPeriod")
+	if period <= 0 {
+		period = defaultCPUPeriod
+	}
+	return quota / period
+}
+
+// numberField reads a numeric field of a decoded request body, or 0
+func numberField(object map[string]interface{}, field string) float64 {
+	value, _ := toNumber(object[field])
+	return value
+}
+
+// toNumber converts a decoded JSON number. Request bodies are decoded with
+// UseNumber, so that large integers reach the daemon unchanged.
+func toNumber(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case json.Number:
+		f, err := number.Float64()
+		return f, err == nil
+	case float64:
+		return number, true
+	default:
+		return 0, false
+	}
+}
+
+// formatNumber prints a limit without a useless decimal part
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// parseMemorySize parses a memory size as the Docker CLI does: a number of
+// bytes with an optional b, k, m, g or t suffix (binary units)
+func parseMemorySize(size string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(size))
+	value = strings.TrimSuffix(value, "b")
+	if value == "" {
+		return 0, fmt.Errorf("invalid memory size %q", size)
+	}
+
+	multiplier := int64(1)
+	switch value[len(value)-1] {
+	case 'k':
+		multiplier = 1 << 10
+	case 'm':
+		multiplier = 1 << 20
+	case 'g':
+		multiplier = 1 << 30
+	case 't':
+		multiplier = 1 << 40
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid memory size %q", size)
+	}
+	return int64(number * float64(multiplier)), nil
+}
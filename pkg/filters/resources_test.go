@@ -0,0 +1,99 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreateResources(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			Resources: &ResourceFilter{
+				MaxMemory:    "2g",
+				MaxCPUs:      2,
+				MaxPidsLimit: 512,
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		hostConfig    string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"Within limits", `{"Memory":1073741824,"NanoCpus":1500000000,"PidsLimit":256}`, true, ""},
+		{"At the limits", `{"Memory":2147483648,"CpuQuota":200000,"CpuPeriod":100000,"PidsLimit":512}`, true, ""},
+		{"CPU quota with default period", `{"Memory":1073741824,"CpuQuota":150000,"PidsLimit":256}`, true, ""},
+		{"No limits", `{}`, false, "memory limit is required"},
+		{"Memory above maximum", `{"Memory":4294967296,"NanoCpus":1000000000,"PidsLimit":256}`, false, "memory limit exceeds maximum: 4294967296 > 2g"},
+		{"No CPU limit", `{"Memory":1073741824,"PidsLimit":256}`, false, "CPU limit is required"},
+		{"CPUs above maximum", `{"Memory":1073741824,"NanoCpus":4000000000,"PidsLimit":256}`, false, "CPU limit exceeds maximum: 4 > 2"},
+		{"CPU quota above maximum", `{"Memory":1073741824,"CpuQuota":300000,"CpuPeriod":100000,"PidsLimit":256}`, false, "CPU limit exceeds maximum: 3 > 2"},
+		{"Unlimited PIDs", `{"Memory":1073741824,"NanoCpus":1000000000,"PidsLimit":-1}`, false, "PIDs limit is required"},
+		{"PIDs above maximum", `{"Memory":1073741824,"NanoCpus":1000000000,"PidsLimit":4096}`, false, "PIDs limit exceeds maximum: 4096 > 512"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := containerConfig(t, `{"Image":"nginx","HostConfig":`+tt.hostConfig+`}`)
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", config)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckContainerCreateRequiredResources(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			Resources: &ResourceFilter{RequireMemory: true, RequirePidsLimit: true},
+		},
+	}
+
+	config := containerConfig(t, `{"Image":"nginx","HostConfig":{"Memory":68719476736,"PidsLimit":100000}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+		t.Errorf("Expected any limit to be accepted without maximum, got: %s", reason)
+	}
+
+	config = containerConfig(t, `{"Image":"nginx","HostConfig":{"Memory":68719476736}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); allowed || reason != "PIDs limit is required" {
+		t.Errorf("Expected missing PIDs limit to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+
+	invalid := &AdvancedFilter{Containers: &ContainerFilter{Resources: &ResourceFilter{MaxMemory: "lots"}}}
+	if allowed, _ := invalid.CheckContainerCreate("nginx", "web", config); allowed {
+		t.Error("Expected an invalid maximum to deny the creation")
+	}
+}
+
+func TestParseMemorySize(t *testing.T) {
+	tests := []struct {
+		size     string
+		expected int64
+		valid    bool
+	}{
+		{"1024", 1024, true},
+		{"512m", 512 << 20, true},
+		{"2g", 2 << 30, true},
+		{"2GB", 2 << 30, true},
+		{"1.5g", 3 << 29, true},
+		{"64k", 64 << 10, true},
+		{"", 0, false},
+		{"lots", 0, false},
+		{"-1g", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			result, err := parseMemorySize(tt.size)
+			if (err == nil) != tt.valid {
+				t.Fatalf("Expected valid=%v, got error %v", tt.valid, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, result)
+			}
+		})
+	}
+}
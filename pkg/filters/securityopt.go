@@ -0,0 +1,105 @@
+package filters
+
+import (
+	"strings"
+)
+
+// unconfinedProfile disables a confinement mechanism ("seccomp=unconfined", "apparmor=unconfined")
+const unconfinedProfile = "unconfined"
+
+// securityOption is one entry of HostConfig.SecurityOpt, split into its key and value
+type securityOption struct {
+	raw   string
+	key   string
+	value string
+}
+
+// parseSecurityOption splits "key=value", or the legacy "key:value", as the daemon does
+func parseSecurityOption(raw string) securityOption {
+	option := securityOption{raw: strings.TrimSpace(raw)}
+	separator := strings.IndexAny(option.raw, "=:")
+	if separator < 0 {
+		option.key = option.raw
+		return option
+	}
+	option.key = option.raw[:separator]
+	option.value = option.raw[separator+1:]
+	return option
+}
+
+// checkSecurityOptions validates HostConfig.SecurityOpt (seccomp, AppArmor,
+// SELinux labels and no-new-privileges)
+func checkSecurityOptions(cf *ContainerFilter, config map[string]interface{}) (bool, string) {
+	if !cf.DenyUnconfined && !cf.RequireNoNewPrivileges && len(cf.AllowedSeccompProfiles) == 0 &&
+		len(cf.AllowedAppArmorProfiles) == 0 && len(cf.AllowedSELinuxLabels) == 0 {
+		return true, ""
+	}
+
+	var options []securityOption
+	if hostConfig, ok := config["HostConfig"].(map[string]interface{}); ok {
+		items, _ := hostConfig["SecurityOpt"].([]interface{})
+		for _, item := range items {
+			if raw, ok := item.(string); ok {
+				options = append(options, parseSecurityOption(raw))
+			}
+		}
+	}
+
+	noNewPrivileges := false
+	for _, option := range options {
+		switch option.key {
+		case "seccomp":
+			if option.value == unconfinedProfile && cf.DenyUnconfined {
+				return false, "security option is denied: " + option.raw
+			}
+			if ok, _ := checkAllowedList(cf.AllowedSeccompProfiles, option.value, ""); !ok {
+				return false, "seccomp profile not in allowed list: " + seccompProfileName(option.value)
+			}
+		case "apparmor":
+			if option.value == unconfinedProfile && cf.DenyUnconfined {
+				return false, "security option is denied: " + option.raw
+			}
+			if ok, msg := checkAllowedList(cf.AllowedAppArmorProfiles, option.value, "apparmor profile not in allowed list"); !ok {
+				return false, msg
+			}
+		case "label":
+			if option.value == "disable" && cf.DenyUnconfined {
+				return false, "security option is denied: " + option.raw
+			}
+			if ok, msg := checkAllowedList(cf.AllowedSELinuxLabels, option.value, "selinux label not in allowed list"); !ok {
+				return false, msg
+			}
+		case "systempaths":
+			// Unmasks /proc and /sys paths hidden from containers
+			if option.value == unconfinedProfile && cf.DenyUnconfined {
+				return false, "security option is denied: " + option.raw
+			}
+		case "no-new-privileges":
+			noNewPrivileges = option.value == "" || parseOptionBool(option.value)
+		}
+	}
+
+	if cf.RequireNoNewPrivileges && !noNewPrivileges {
+		return false, "security option no-new-privileges is required"
+	}
+
+	return true, ""
+}
+
+// seccompProfileName shortens the profiles sent inline: the Docker CLI replaces
+// "seccomp=<file>" by the JSON content of the file
+func seccompProfileName(profile string) string {
+	if strings.HasPrefix(strings.TrimSpace(profile), "{") {
+		return "<inline profile>"
+	}
+	return profile
+}
+
+// parseOptionBool parses a boolean security option value as the daemon does
+func parseOptionBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "t", "true":
+		return true
+	}
+	return false
+}
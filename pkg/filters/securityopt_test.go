@@ -0,0 +1,77 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreateSecurityOptions(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			DenyUnconfined:          true,
+			AllowedAppArmorProfiles: []string{"^docker-default$", "^app-.*"},
+			AllowedSELinuxLabels:    []string{"^type:svirt_apache_t$", "^level:s0:c[0-9]+,c[0-9]+$"},
+			RequireNoNewPrivileges:  true,
+		},
+	}
+
+	tests := []struct {
+		name          string
+		securityOpt   string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"No options", `[]`, false, "security option no-new-privileges is required"},
+		{"No new privileges", `["no-new-privileges"]`, true, ""},
+		{"No new privileges true", `["no-new-privileges=true"]`, true, ""},
+		{"No new privileges legacy", `["no-new-privileges:true"]`, true, ""},
+		{"No new privileges false", `["no-new-privileges=false"]`, false, "security option no-new-privileges is required"},
+		{"Unconfined seccomp", `["no-new-privileges","seccomp=unconfined"]`, false, "security option is denied: seccomp=unconfined"},
+		{"Unconfined apparmor legacy", `["apparmor:unconfined","no-new-privileges"]`, false, "security option is denied: apparmor:unconfined"},
+		{"Disabled SELinux", `["label=disable","no-new-privileges"]`, false, "security option is denied: label=disable"},
+		{"Unmasked system paths", `["systempaths=unconfined","no-new-privileges"]`, false, "security option is denied: systempaths=unconfined"},
+		{"Allowed apparmor profile", `["apparmor=app-web","no-new-privileges"]`, true, ""},
+		{"Other apparmor profile", `["apparmor=custom","no-new-privileges"]`, false, "apparmor profile not in allowed list: custom"},
+		{"Allowed SELinux type", `["label=type:svirt_apache_t","no-new-privileges"]`, true, ""},
+		{"Allowed SELinux level", `["label=level:s0:c100,c200","no-new-privileges"]`, true, ""},
+		{"Other SELinux type", `["label=type:spc_t","no-new-privileges"]`, false, "selinux label not in allowed list: type:spc_t"},
+		{"Inline seccomp profile", `["seccomp={\"defaultAction\":\"SCMP_ACT_ALLOW\"}","no-new-privileges"]`, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := containerConfig(t, `{"Image":"nginx","HostConfig":{"SecurityOpt":`+tt.securityOpt+`}}`)
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", config)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckContainerCreateSeccompProfiles(t *testing.T) {
+	filter := &AdvancedFilter{Containers: &ContainerFilter{AllowedSeccompProfiles: []string{"^builtin$"}}}
+
+	config := containerConfig(t, `{"Image":"nginx","HostConfig":{"SecurityOpt":["seccomp=builtin"]}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+		t.Errorf("Expected builtin seccomp profile to be allowed, got: %s", reason)
+	}
+
+	// Without deny_unconfined, the allow-list alone refuses unconfined
+	config = containerConfig(t, `{"Image":"nginx","HostConfig":{"SecurityOpt":["seccomp=unconfined"]}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); allowed || reason != "seccomp profile not in allowed list: unconfined" {
+		t.Errorf("Expected unconfined seccomp to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+
+	// Inline profiles are not repeated in the reason
+	config = containerConfig(t, `{"Image":"nginx","HostConfig":{"SecurityOpt":["seccomp={\"defaultAction\":\"SCMP_ACT_ALLOW\"}"]}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); allowed || reason != "seccomp profile not in allowed list: <inline profile>" {
+		t.Errorf("Expected inline seccomp profile to be denied, got allowed=%v reason=%s", allowed, reason)
+	}
+
+	// No security option keeps the default profile
+	config = containerConfig(t, `{"Image":"nginx","HostConfig":{}}`)
+	if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+		t.Errorf("Expected default profile to be allowed, got: %s", reason)
+	}
+}
@@ -0,0 +1,110 @@
+package filters
+
+import (
+	"strconv"
+)
+
+// UpdateFilter defines the changes allowed on a running container
+// (POST /containers/{id}/update, i.e. docker update)
+type UpdateFilter struct {
+	DenyAll   bool            `json:"deny_all,omitempty"`  // Deny any container update
+	Resources *ResourceFilter `json:"resources,omitempty"` // Resource limits; the container ones apply when unset
+}
+
+// RestartPolicyFilter defines the restart policies a container may use,
+// on creation (HostConfig.RestartPolicy) and on update (RestartPolicy)
+type RestartPolicyFilter struct {
+	AllowedPolicies []string `json:"allowed_policies,omitempty"` // "no", "always", "unless-stopped", "on-failure"
+	MaxRetries      int      `json:"max_retries,omitempty"`      // Maximum retry count of "on-failure"
+}
+
+// CheckContainerUpdate checks if a container update is allowed.
+// Fields absent from the body are left unchanged by the daemon and are not checked.
+func (af *AdvancedFilter) CheckContainerUpdate(update map[string]interface{}) (bool, string) {
+	if af.Updates != nil && af.Updates.DenyAll {
+		return false, "container updates are denied"
+	}
+
+	// Resource ceilings of the creation also bound the updates
+	var rf *ResourceFilter
+	if af.Updates != nil && af.Updates.Resources != nil {
+		rf = af.Updates.Resources
+	} else if af.Containers != nil {
+		rf = af.Containers.Resources
+	}
+	if ok, msg := checkResourceUpdate(rf, update); !ok {
+		return false, msg
+	}
+
+	policy, _ := update["RestartPolicy"].(map[string]interface{})
+	return checkRestartPolicy(af.RestartPolicy, policy)
+}
+
+// checkResourceUpdate validates the limits changed by an update.
+// A limit can be changed within its maximum, but not removed when it is required.
+func checkResourceUpdate(rf *ResourceFilter, update map[string]interface{}) (bool, string) {
+	if rf == nil {
+		return true, ""
+	}
+
+	if memory, ok := toNumber(update["Memory"]); ok {
+		if memory < 0 && (rf.RequireMemory || rf.MaxMemory != "") {
+			return false, "memory limit is required"
+		}
+		if memory > 0 && rf.MaxMemory != "" {
+			maxMemory, err := parseMemorySize(rf.MaxMemory)
+			if err != nil {
+				return false, "invalid max_memory in filter configuration: " + rf.MaxMemory
+			}
+			if memory > float64(maxMemory) {
+				return false, "memory limit exceeds maximum: " + formatNumber(memory) + " > " + rf.MaxMemory
+			}
+		}
+	}
+
+	cpuRequired := rf.RequireCPU || rf.MaxCPUs > 0
+	if quota, ok := toNumber(update["CpuQuota"]); ok && quota < 0 && cpuRequired {
+		return false, "CPU limit is required"
+	}
+	if cpus := containerCPUs(update); cpus > 0 && rf.MaxCPUs > 0 && cpus > rf.MaxCPUs {
+		return false, "CPU limit exceeds maximum: " + formatNumber(cpus) + " > " + formatNumber(rf.MaxCPUs)
+	}
+
+	// 0 and -1 both remove the PIDs limit
+	if pidsLimit, ok := toNumber(update["PidsLimit"]); ok {
+		if pidsLimit <= 0 && (rf.RequirePidsLimit || rf.MaxPidsLimit > 0) {
+			return false, "PIDs limit is required"
+		}
+		if rf.MaxPidsLimit > 0 && pidsLimit > float64(rf.MaxPidsLimit) {
+			return false, "PIDs limit exceeds maximum: " + formatNumber(pidsLimit) + " > " + strconv.FormatInt(rf.MaxPidsLimit, 10)
+		}
+	}
+
+	return true, ""
+}
+
+// checkRestartPolicy validates a restart policy ({"Name": ..., "MaximumRetryCount": ...})
+func checkRestartPolicy(rp *RestartPolicyFilter, policy map[string]interface{}) (bool, string) {
+	if rp == nil || policy == nil {
+		return true, ""
+	}
+
+	name, _ := policy["Name"].(string)
+	if name == "" {
+		name = "no"
+	}
+	if len(rp.AllowedPolicies) > 0 && !contains(rp.AllowedPolicies, name) {
+		return false, "restart policy not allowed: " + name
+	}
+
+	// "on-failure" without a retry count restarts the container forever
+	retries, _ := toNumber(policy["MaximumRetryCount"])
+	if rp.MaxRetries > 0 && name == "on-failure" && retries <= 0 {
+		return false, "restart retry count is required"
+	}
+	if rp.MaxRetries > 0 && retries > float64(rp.MaxRetries) {
+		return false, "restart retry count exceeds maximum: " + formatNumber(retries) + " > " + strconv.Itoa(rp.MaxRetries)
+	}
+
+	return true, ""
+}
@@ -0,0 +1,102 @@
+package filters
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxID is the largest Linux UID or GID
+const maxID = 1<<32 - 1
+
+// UserFilter defines the user a container may run as (Config.User, i.e. --user)
+type UserFilter struct {
+	DenyRoot     bool     `json:"deny_root,omitempty"`     // Deny root, "0" and an unset user (the image default, root unless inspected)
+	AllowedUIDs  []string `json:"allowed_uids,omitempty"`  // Allowed UIDs or ranges, e.g. "1000-1999"; user names are then denied
+	AllowedGIDs  []string `json:"allowed_gids,omitempty"`  // Allowed GIDs or ranges; the group must then be set
+	InspectImage bool     `json:"inspect_image,omitempty"` // Check the user configured in the image when the request does not set one
+}
+
+// ImageUserNeeded reports whether the user of the image must be looked up
+// before checking a container creation: the request leaves User unset and the
+// filter inspects images
+func (af *AdvancedFilter) ImageUserNeeded(config map[string]interface{}) bool {
+	if af.Containers == nil || af.Containers.User == nil || !af.Containers.User.InspectImage {
+		return false
+	}
+	user, _ := config["User"].(string)
+	return strings.TrimSpace(user) == ""
+}
+
+// checkUser validates the user and group of a container
+func checkUser(uf *UserFilter, config map[string]interface{}) (bool, string) {
+	if uf == nil {
+		return true, ""
+	}
+
+	user, _ := config["User"].(string)
+	user = strings.TrimSpace(user)
+	name, group, _ := strings.Cut(user, ":")
+
+	if uf.DenyRoot {
+		if name == "" {
+			return false, "container user is not set (runs as root)"
+		}
+		if name == "root" || isRootID(name) {
+			return false, "container user is root: " + user
+		}
+	}
+
+	if len(uf.AllowedUIDs) > 0 {
+		if name == "" {
+			return false, "container user is not set (runs as root)"
+		}
+		uid, ok := parseID(name)
+		if !ok {
+			return false, "container user must be a numeric UID: " + name
+		}
+		if !idAllowed(uf.AllowedUIDs, uid) {
+			return false, "UID not in allowed list: " + name
+		}
+	}
+
+	if len(uf.AllowedGIDs) > 0 {
+		if group == "" {
+			return false, "container group must be set: " + user
+		}
+		gid, ok := parseID(group)
+		if !ok {
+			return false, "container group must be a numeric GID: " + group
+		}
+		if !idAllowed(uf.AllowedGIDs, gid) {
+			return false, "GID not in allowed list: " + group
+		}
+	}
+
+	return true, ""
+}
+
+// isRootID reports whether a numeric user is UID 0 ("0", "00"...)
+func isRootID(name string) bool {
+	id, ok := parseID(name)
+	return ok && id == 0
+}
+
+// parseID parses a numeric UID or GID
+func parseID(value string) (int64, bool) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int64(id), true
+}
+
+// idAllowed reports whether an ID belongs to one of the allowed IDs or ranges
+func idAllowed(allowed []string, id int64) bool {
+	for _, entry := range allowed {
+		first, last, ok := parseRange(entry, maxID)
+		if ok && strings.TrimSpace(entry) != "" && id >= first && id <= last {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package filters
+
+import "testing"
+
+func TestCheckContainerCreateUser(t *testing.T) {
+	filter := &AdvancedFilter{
+		Containers: &ContainerFilter{
+			User: &UserFilter{
+				DenyRoot:    true,
+				AllowedUIDs: []string{"1000-1999", "65534"},
+				AllowedGIDs: []string{"1000-1999"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		user          string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{"UID and GID in range", "1000:1000", true, ""},
+		{"Single allowed UID", "65534:1500", true, ""},
+		{"Unset user", "", false, "container user is not set (runs as root)"},
+		{"Root by name", "root", false, "container user is root: root"},
+		{"Root by UID", "0:1000", false, "container user is root: 0:1000"},
+		{"Root with leading zeros", "000", false, "container user is root: 000"},
+		{"UID out of range", "2000:1000", false, "UID not in allowed list: 2000"},
+		{"User name", "nginx:1000", false, "container user must be a numeric UID: nginx"},
+		{"Missing group", "1000", false, "container group must be set: 1000"},
+		{"Root group", "1000:0", false, "GID not in allowed list: 0"},
+		{"Group name", "1000:staff", false, "container group must be a numeric GID: staff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := containerConfig(t, `{"Image":"nginx","User":"`+tt.user+`"}`)
+			allowed, reason := filter.CheckContainerCreate("nginx", "web", config)
+			if allowed != tt.expectAllowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.expectAllowed, allowed, reason)
+			}
+			if reason != tt.expectReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectReason, reason)
+			}
+		})
+	}
+}
+
+func TestCheckContainerCreateDenyRootOnly(t *testing.T) {
+	filter := &AdvancedFilter{Containers: &ContainerFilter{User: &UserFilter{DenyRoot: true}}}
+
+	for _, user := range []string{"nginx", "www-data:www-data", "1000"} {
+		config := containerConfig(t, `{"Image":"nginx","User":"`+user+`"}`)
+		if allowed, reason := filter.CheckContainerCreate("nginx", "web", config); !allowed {
+			t.Errorf("Expected user %s to be allowed, got: %s", user, reason)
+		}
+	}
+
+	config := containerConfig(t, `{"Image":"nginx"}`)
+	if allowed, _ := filter.CheckContainerCreate("nginx", "web", config); allowed {
+		t.Error("Expected a container without user to be denied")
+	}
+}
+
+func TestImageUserNeeded(t *testing.T) {
+	inspecting := &AdvancedFilter{Containers: &ContainerFilter{User: &UserFilter{DenyRoot: true, InspectImage: true}}}
+	notInspecting := &AdvancedFilter{Containers: &ContainerFilter{User: &UserFilter{DenyRoot: true}}}
+
+	if !inspecting.ImageUserNeeded(containerConfig(t, `{"Image":"nginx"}`)) {
+		t.Error("Expected the image user to be needed without User")
+	}
+	if inspecting.ImageUserNeeded(containerConfig(t, `{"Image":"nginx","User":"1000"}`)) {
+		t.Error("Expected the image user not to be needed with User")
+	}
+	if notInspecting.ImageUserNeeded(containerConfig(t, `{"Image":"nginx"}`)) {
+		t.Error("Expected the image user not to be needed without inspect_image")
+	}
+	if (&AdvancedFilter{}).ImageUserNeeded(containerConfig(t, `{"Image":"nginx"}`)) {
+		t.Error("Expected the image user not to be needed without container filters")
+	}
+}
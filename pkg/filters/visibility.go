@@ -0,0 +1,39 @@
+package filters
+
+import (
+	"strings"
+)
+
+// VisibilityFilter restricts the containers, images, volumes and networks a
+// caller can see: list answers are trimmed to the matching objects, and the
+// inspection of other objects is answered as if they did not exist.
+// An object is visible when it matches the label selector or one of the names.
+type VisibilityFilter struct {
+	Labels map[string]string `json:"labels,omitempty"` // Labels of the visible objects, with a value pattern
+	Names  []string          `json:"names,omitempty"`  // Names of the visible objects (patterns); image tags for images
+}
+
+// VisibilityEnabled reports whether list and inspect answers must be filtered
+func (af *AdvancedFilter) VisibilityEnabled() bool {
+	return af.Visibility != nil && (len(af.Visibility.Labels) > 0 || len(af.Visibility.Names) > 0)
+}
+
+// ObjectVisible reports whether an object can be seen by the caller, from its
+// names (container names, image tags...) and labels
+func (af *AdvancedFilter) ObjectVisible(names []string, labels map[string]string) bool {
+	if !af.VisibilityEnabled() {
+		return true
+	}
+
+	vf := af.Visibility
+	if len(vf.Labels) > 0 && labelsMatch(vf.Labels, labels) {
+		return true
+	}
+	for _, name := range names {
+		// Docker reports container names with a leading slash
+		if matchesAny(vf.Names, strings.TrimPrefix(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
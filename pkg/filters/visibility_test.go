@@ -0,0 +1,47 @@
+package filters
+
+import "testing"
+
+func TestObjectVisible(t *testing.T) {
+	filter := &AdvancedFilter{
+		Visibility: &VisibilityFilter{
+			Labels: map[string]string{"com.docker.compose.project": "^shop$"},
+			Names:  []string{"^shop-"},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		names         []string
+		labels        map[string]string
+		expectVisible bool
+	}{
+		{"Matching labels", []string{"/db"}, map[string]string{"com.docker.compose.project": "shop"}, true},
+		{"Matching container name", []string{"/shop-web"}, nil, true},
+		{"Matching volume name", []string{"shop-data"}, nil, true},
+		{"Other project", []string{"/db"}, map[string]string{"com.docker.compose.project": "billing"}, false},
+		{"Nothing matches", []string{"/billing-web"}, nil, false},
+		{"No names", nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if visible := filter.ObjectVisible(tt.names, tt.labels); visible != tt.expectVisible {
+				t.Errorf("Expected visible=%v, got %v", tt.expectVisible, visible)
+			}
+		})
+	}
+
+	t.Run("Names only", func(t *testing.T) {
+		names := &AdvancedFilter{Visibility: &VisibilityFilter{Names: []string{"^shop-"}}}
+		if names.ObjectVisible([]string{"/db"}, map[string]string{}) {
+			t.Error("Expected an object without a matching name to be hidden")
+		}
+	})
+
+	t.Run("Without visibility filter", func(t *testing.T) {
+		if !(&AdvancedFilter{}).ObjectVisible(nil, nil) {
+			t.Error("Expected every object to be visible")
+		}
+	})
+}
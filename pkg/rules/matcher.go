@@ -63,6 +63,7 @@ func (m *Matcher) isPathAllowed(path string) bool {
 		regexp.MustCompile(`^/containers`):   m.rules.Containers,
 		regexp.MustCompile(`^/distribution`): m.rules.Distribution,
 		regexp.MustCompile(`^/exec`):         m.rules.Exec,
+		regexp.MustCompile(`^/grpc`):         m.rules.Build, // BuildKit build sessions
 		regexp.MustCompile(`^/images`):       m.rules.Images,
 		regexp.MustCompile(`^/info`):         m.rules.Info,
 		regexp.MustCompile(`^/networks`):     m.rules.Networks,
@@ -187,6 +187,18 @@ func TestIsPathAllowed(t *testing.T) {
 			rules:    &config.AccessRules{Exec: true},
 			expected: true,
 		},
+		{
+			name:     "BuildKit grpc endpoint follows build",
+			path:     "/grpc",
+			rules:    &config.AccessRules{Build: true},
+			expected: true,
+		},
+		{
+			name:     "BuildKit grpc endpoint denied without build",
+			path:     "/grpc",
+			rules:    &config.AccessRules{Session: true},
+			expected: false,
+		},
 		{
 			name:     "Unknown endpoint denied",
 			path:     "/v1.41/unknown/endpoint",